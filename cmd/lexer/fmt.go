@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adroge/lexer/format"
+)
+
+// runFmt formats the meta blocks of its inputs, writing the result to stdout
+// or back to the files, and returns 1 when any input could not be formatted
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: lexer fmt [-l] [-w] [-s] [meta flags] [files...|-]")
+		flags.PrintDefaults()
+	}
+	list := flags.Bool("l", false, "list the files whose formatting differs instead of printing them")
+	write := flags.Bool("w", false, "write the result to the files instead of printing it")
+	sortKeys := flags.Bool("s", false, "sort the entries of each meta block by key")
+	var meta metaFlags
+	meta.register(flags)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	lexerOpts, err := meta.options()
+	if err != nil {
+		fmt.Fprintf(stderr, "lexer fmt: %s\n", err)
+		return 2
+	}
+	opts := []format.Option{format.WithLexerOptions(lexerOpts...)}
+	if *sortKeys {
+		opts = append(opts, format.WithSortedKeys())
+	}
+
+	status := 0
+	for _, name := range inputNames(flags.Args()) {
+		if err := formatFile(name, stdin, stdout, *list, *write, opts); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", name, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// formatFile formats the file called name as asked by list and write
+func formatFile(name string, stdin io.Reader, stdout io.Writer, list, write bool, opts []format.Option) error {
+	text, err := readInput(name, stdin)
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Format(text, opts...)
+	if err != nil {
+		return err
+	}
+
+	if list && formatted != text {
+		fmt.Fprintln(stdout, name)
+	}
+	if write && name == "-" {
+		return errors.New("cannot write the result to the standard input")
+	}
+	if write && formatted != text {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(name, []byte(formatted), info.Mode().Perm())
+	}
+	if !list && !write {
+		_, err = io.WriteString(stdout, formatted)
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+)
+
+func TestFmtStdin(t *testing.T) {
+	code, stdout, stderr := runWith([]string{"fmt"}, "a {{ y : 2 , x:1 }} b\n")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "a {{y: 2, x: 1}} b\n", stdout)
+	assert.Empty(t, stderr)
+
+	code, stdout, _ = runWith([]string{"fmt", "-s", "-"}, "{{ y : 2 , x:1 }}")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "{{x: 1, y: 2}}", stdout)
+}
+
+func TestFmtMetaFlags(t *testing.T) {
+	code, stdout, _ := runWith([]string{"fmt", "-left", "<<", "-right", ">>", "-indicator", "=", "-separator", "|"}, "<< a=1|b >>")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "<<a= 1| b>>", stdout)
+
+	// the flags do not change the defaults of other lexers
+	l := lexer.Create("{{a}}")
+	assert.Equal(t, lexer.TokenLeftMeta, l.NextToken().Type)
+}
+
+func TestFmtFiles(t *testing.T) {
+	dir := t.TempDir()
+	messy := filepath.Join(dir, "messy.txt")
+	tidy := filepath.Join(dir, "tidy.txt")
+	broken := filepath.Join(dir, "broken.txt")
+	assert.Nil(t, os.WriteFile(messy, []byte("{{a:1}}"), 0o644))
+	assert.Nil(t, os.WriteFile(tidy, []byte("{{a: 1}}"), 0o644))
+	assert.Nil(t, os.WriteFile(broken, []byte("{{a: *}}"), 0o644))
+
+	code, stdout, _ := runWith([]string{"fmt", "-l", messy, tidy}, "")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, messy+"\n", stdout)
+
+	code, stdout, _ = runWith([]string{"fmt", "-w", messy, tidy}, "")
+	assert.Equal(t, 0, code)
+	assert.Empty(t, stdout)
+	text, err := os.ReadFile(messy)
+	assert.Nil(t, err)
+	assert.Equal(t, "{{a: 1}}", string(text))
+
+	code, stdout, stderr := runWith([]string{"fmt", broken, tidy}, "")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "{{a: 1}}", stdout)
+	assert.Equal(t, broken+`: 1:6: value syntax: "*"`+"\n", stderr)
+	text, err = os.ReadFile(broken)
+	assert.Nil(t, err)
+	assert.Equal(t, "{{a: *}}", string(text))
+
+	code, _, stderr = runWith([]string{"fmt", filepath.Join(dir, "missing.txt")}, "")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no such file")
+
+	code, _, stderr = runWith([]string{"fmt", "-w"}, "{{a:1}}")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "cannot write the result to the standard input")
+}
@@ -0,0 +1,125 @@
+// Command lexer works with documents of plain text and meta blocks.
+//
+// Usage:
+//
+//	lexer fmt [-l] [-w] [-s] [meta flags] [files...|-]
+//	lexer tokens [-o table|json|ndjson] [meta flags] [files...|-]
+//
+// Files are read in turn, or the standard input when there are none or
+// the file is -. The meta flags set the delimiters, value indicator and
+// separator of the lexer:
+//
+//	-left {{ -right }} -indicator : -separator ,
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"github.com/adroge/lexer"
+)
+
+const usage = `usage: lexer <command> [flags] [files...|-]
+
+commands:
+  fmt      rewrite meta blocks in the canonical layout
+  tokens   print the tokens of the input as a table, JSON or NDJSON
+
+Run "lexer <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command of args and returns the exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "fmt":
+		return runFmt(args[1:], stdin, stdout, stderr)
+	case "tokens":
+		return runTokens(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	fmt.Fprintf(stderr, "lexer: unknown command %q\n%s", args[0], usage)
+	return 2
+}
+
+// metaFlags are the flags setting the meta values of the lexer
+type metaFlags struct {
+	left, right          string
+	indicator, separator string
+}
+
+func (m *metaFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&m.left, "left", "{{", "left meta delimiter")
+	flags.StringVar(&m.right, "right", "}}", "right meta delimiter")
+	flags.StringVar(&m.indicator, "indicator", ":", "value indicator")
+	flags.StringVar(&m.separator, "separator", ",", "value separator")
+}
+
+// options returns the lexer options setting the meta values of the flags
+func (m *metaFlags) options() ([]lexer.Option, error) {
+	indicator, err := singleRune("indicator", m.indicator)
+	if err != nil {
+		return nil, err
+	}
+	separator, err := singleRune("separator", m.separator)
+	if err != nil {
+		return nil, err
+	}
+	opts := []lexer.Option{
+		lexer.WithDelimiters(m.left, m.right),
+		lexer.WithValueIndicator(indicator),
+		lexer.WithSeparator(separator),
+	}
+
+	// a lexer reports an invalid configuration as its first token
+	l := lexer.Create("", opts...)
+	if token := l.NextToken(); token.Type == lexer.TokenError {
+		return nil, errors.New(token.Value)
+	}
+	return opts, nil
+}
+
+func singleRune(name, value string) (rune, error) {
+	r, size := utf8.DecodeRuneInString(value)
+	if size == 0 || size != len(value) {
+		return 0, fmt.Errorf("-%s must be a single character, not %q", name, value)
+	}
+	return r, nil
+}
+
+// inputNames returns the names of the files to read, "-" being the
+// standard input, which is read when there are none
+func inputNames(args []string) []string {
+	if len(args) == 0 {
+		return []string{"-"}
+	}
+	return args
+}
+
+// readInput returns the text of the file called name, or of stdin for "-"
+func readInput(name string, stdin io.Reader) (string, error) {
+	var (
+		text []byte
+		err  error
+	)
+	if name == "-" {
+		text, err = io.ReadAll(stdin)
+	} else {
+		text, err = os.ReadFile(name)
+	}
+	return string(text), err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runWith runs args with stdin and returns the exit code and the output
+func runWith(args []string, stdin string) (code int, stdout, stderr string) {
+	var out, errOut strings.Builder
+	code = run(args, strings.NewReader(stdin), &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRunUsage(t *testing.T) {
+	code, _, stderr := runWith(nil, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "usage: lexer <command>")
+
+	code, stdout, _ := runWith([]string{"help"}, "")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "fmt")
+
+	code, _, stderr = runWith([]string{"bogus"}, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "bogus"`)
+}
+
+func TestRunMetaFlags(t *testing.T) {
+	code, _, stderr := runWith([]string{"fmt", "-indicator", "::"}, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `-indicator must be a single character, not "::"`)
+
+	code, _, stderr = runWith([]string{"fmt", "-separator", ":"}, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "indicator cannot match separator")
+}
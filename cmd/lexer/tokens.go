@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/adroge/lexer"
+)
+
+// jsonPosition is a lexer.Position as it is written in JSON
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// jsonToken is a token as it is written in JSON
+type jsonToken struct {
+	File  string       `json:"file"`
+	Type  string       `json:"type"`
+	Value string       `json:"value"`
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+func newJSONToken(file string, token lexer.Token) jsonToken {
+	return jsonToken{
+		File:  file,
+		Type:  token.Type.String(),
+		Value: token.Value,
+		Start: jsonPosition(token.Start),
+		End:   jsonPosition(token.End),
+	}
+}
+
+// runTokens prints the tokens of its inputs, and returns 1 when any input
+// has a syntax error or could not be read
+func runTokens(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: lexer tokens [-o table|json|ndjson] [meta flags] [files...|-]")
+		flags.PrintDefaults()
+	}
+	output := flags.String("o", "table", "output format: table, json or ndjson")
+	var meta metaFlags
+	meta.register(flags)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	opts, err := meta.options()
+	if err != nil {
+		fmt.Fprintf(stderr, "lexer tokens: %s\n", err)
+		return 2
+	}
+
+	var (
+		table   *tabwriter.Writer
+		encoder *json.Encoder
+		all     = []jsonToken{} // for json, written as an array at the end
+	)
+	switch *output {
+	case "table":
+		table = tabwriter.NewWriter(stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(table, "POSITION\tTYPE\tVALUE")
+	case "json":
+	case "ndjson":
+		encoder = json.NewEncoder(stdout)
+	default:
+		fmt.Fprintf(stderr, "lexer tokens: unknown output format %q\n", *output)
+		return 2
+	}
+
+	status := 0
+	for _, name := range inputNames(flags.Args()) {
+		err := tokenize(name, stdin, opts, func(token lexer.Token) error {
+			switch {
+			case table != nil:
+				_, err := fmt.Fprintf(table, "%s:%s\t%s\t%q\n", name, token.Start, token.Type, token.Value)
+				return err
+			case encoder != nil:
+				return encoder.Encode(newJSONToken(name, token))
+			}
+			all = append(all, newJSONToken(name, token))
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", name, err)
+			status = 1
+		}
+	}
+
+	switch *output {
+	case "table":
+		err = table.Flush()
+	case "json":
+		var text []byte
+		if text, err = json.MarshalIndent(all, "", "  "); err == nil {
+			_, err = fmt.Fprintf(stdout, "%s\n", text)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "lexer tokens: %s\n", err)
+		return 1
+	}
+	return status
+}
+
+// tokenize passes the tokens of the file called name, or of stdin for "-",
+// lexed with opts to print, including the end of the input and any error
+// token. It returns the syntax error, if any.
+func tokenize(name string, stdin io.Reader, opts []lexer.Option, print func(lexer.Token) error) error {
+	reader := stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	l := lexer.CreateFromReader(reader, opts...)
+	for token, err := range l.All(context.Background()) {
+		if printErr := print(token); printErr != nil {
+			return printErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// All stops before the end of the input, which Scan leaves in Token
+	return print(l.Token())
+}
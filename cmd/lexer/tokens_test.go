@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+)
+
+func TestTokensTable(t *testing.T) {
+	code, stdout, stderr := runWith([]string{"tokens"}, "a {{x: 1}}")
+	assert.Equal(t, 0, code)
+	assert.Empty(t, stderr)
+	assert.Equal(t, `POSITION  TYPE             VALUE
+-:1:1     PlainText        "a "
+-:1:3     LeftMeta         "{{"
+-:1:5     MetaIdentifier   "x"
+-:1:8     MetaNumberValue  "1"
+-:1:9     RightMeta        "}}"
+-:1:11    Eof              ""
+`, stdout)
+}
+
+func TestTokensJSON(t *testing.T) {
+	code, stdout, _ := runWith([]string{"tokens", "-o", "json"}, "{{x}}")
+	assert.Equal(t, 0, code)
+
+	var tokens []jsonToken
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &tokens))
+	assert.Equal(t, []jsonToken{
+		{File: "-", Type: "LeftMeta", Value: "{{", Start: jsonPosition{0, 1, 1}, End: jsonPosition{2, 1, 3}},
+		{File: "-", Type: "MetaIdentifier", Value: "x", Start: jsonPosition{2, 1, 3}, End: jsonPosition{3, 1, 4}},
+		{File: "-", Type: "RightMeta", Value: "}}", Start: jsonPosition{3, 1, 4}, End: jsonPosition{5, 1, 6}},
+		{File: "-", Type: "Eof", Start: jsonPosition{5, 1, 6}, End: jsonPosition{5, 1, 6}},
+	}, tokens)
+
+	code, stdout, _ = runWith([]string{"tokens", "-o", "json"}, "")
+	assert.Equal(t, 0, code)
+	assert.True(t, strings.HasPrefix(stdout, "[\n  {"))
+}
+
+func TestTokensNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	assert.Nil(t, os.WriteFile(first, []byte("<<a=1>>"), 0o644))
+	assert.Nil(t, os.WriteFile(second, []byte("<<b|c>>"), 0o644))
+
+	code, stdout, _ := runWith([]string{"tokens", "-o", "ndjson", "-left", "<<", "-right", ">>",
+		"-indicator", "=", "-separator", "|", first, second}, "")
+	assert.Equal(t, 0, code)
+
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	assert.Len(t, lines, 10)
+	var token jsonToken
+	assert.Nil(t, json.Unmarshal([]byte(lines[2]), &token))
+	assert.Equal(t, jsonToken{File: first, Type: "MetaNumberValue", Value: "1", Start: jsonPosition{4, 1, 5}, End: jsonPosition{5, 1, 6}}, token)
+	assert.Nil(t, json.Unmarshal([]byte(lines[9]), &token))
+	assert.Equal(t, second, token.File)
+	assert.Equal(t, "Eof", token.Type)
+
+	// the flags do not change the defaults of other lexers
+	l := lexer.Create("{{a}}")
+	assert.Equal(t, lexer.TokenLeftMeta, l.NextToken().Type)
+}
+
+func TestTokensError(t *testing.T) {
+	code, stdout, stderr := runWith([]string{"tokens", "-o", "ndjson"}, "{{x: *}} more")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, `"type":"Error","value":"value syntax: \"*\""`)
+	assert.Equal(t, "-: 1:6: value syntax: \"*\"\n", stderr)
+
+	code, _, stderr = runWith([]string{"tokens", filepath.Join(t.TempDir(), "missing.txt")}, "")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no such file")
+
+	code, _, stderr = runWith([]string{"tokens", "-o", "xml"}, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown output format "xml"`)
+}
@@ -0,0 +1,90 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// These are the kinds of LexError, matched with errors.Is.
+var (
+	ErrUnclosedMeta     = errors.New("unclosed meta")
+	ErrIdentifierSyntax = errors.New("identifier syntax")
+	ErrValueSyntax      = errors.New("value syntax")
+	ErrNumberSyntax     = errors.New("number syntax")
+	ErrStringSyntax     = errors.New("string syntax")
+	ErrUnclosedComment  = errors.New("unclosed comment")
+	ErrRead             = errors.New("read error")
+)
+
+// Expected token sets reported with a LexError.
+var (
+	expectRightMeta  = []TokenType{TokenRightMeta}
+	expectIdentifier = []TokenType{TokenMetaIdentifier, TokenRightMeta}
+	expectValue      = []TokenType{TokenMetaNumberValue, TokenMetaTextValue, TokenMetaStringValue, TokenMetaBoolValue, TokenMetaNullValue, TokenMetaQuantityValue}
+	expectNumber     = []TokenType{TokenMetaNumberValue, TokenMetaQuantityValue}
+	expectString     = []TokenType{TokenMetaStringValue}
+	expectComment    = []TokenType{TokenComment}
+)
+
+// LexError is a syntax error found in the input. It is returned by Err
+// after Scan stops on an error token, and is the Err of that token.
+//
+//	if errors.Is(err, lexer.ErrUnclosedMeta) {
+//		...
+//	}
+type LexError struct {
+	Kind     error       // one of the Err values of this package
+	Start    Position    // where the offending text starts
+	End      Position    // just after the offending text
+	Snippet  string      // the offending text
+	Expected []TokenType // tokens that would have been accepted
+	Message  string
+
+	cause     error // error returned by the reader, for ErrRead
+	recovered bool  // lexing continued after the error
+}
+
+func (e *LexError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Start, e.Message)
+}
+
+// Unwrap returns the kind of error, and for ErrRead the reader's error.
+func (e *LexError) Unwrap() []error {
+	if e.cause != nil {
+		return []error{e.Kind, e.cause}
+	}
+	return []error{e.Kind}
+}
+
+// These are the kinds of UnmarshalError, matched with errors.Is.
+var (
+	ErrUnknownKey = errors.New("unknown key")
+	ErrMissingKey = errors.New("missing key")
+	ErrValueType  = errors.New("value type")
+)
+
+// UnmarshalError is an entry that Unmarshal could not store, or a key
+// that the struct requires but the input does not give.
+type UnmarshalError struct {
+	Kind    error    // one of ErrUnknownKey, ErrMissingKey and ErrValueType
+	Key     string   // the key of the entry
+	Pos     Position // where the key starts, zero for ErrMissingKey
+	Message string
+
+	cause error // error converting the value, for ErrValueType
+}
+
+func (e *UnmarshalError) Error() string {
+	if e.Kind == ErrMissingKey {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// Unwrap returns the kind of error, and the error converting the value if any.
+func (e *UnmarshalError) Unwrap() []error {
+	if e.cause != nil {
+		return []error{e.Kind, e.cause}
+	}
+	return []error{e.Kind}
+}
@@ -0,0 +1,71 @@
+package lexer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+)
+
+func lastToken(l interface{ NextToken() lexer.Token }) (last lexer.Token) {
+	for token := l.NextToken(); token.Type != lexer.TokenUndefined; token = l.NextToken() {
+		last = token
+	}
+	return
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		input    string
+		kind     error
+		snippet  string
+		expected []lexer.TokenType
+	}{
+		{"{{z", lexer.ErrUnclosedMeta, "", []lexer.TokenType{lexer.TokenRightMeta}},
+		{"{{12}}", lexer.ErrIdentifierSyntax, "1", []lexer.TokenType{lexer.TokenMetaIdentifier, lexer.TokenRightMeta}},
+		{"{{z:*}}", lexer.ErrValueSyntax, "*", []lexer.TokenType{lexer.TokenMetaNumberValue, lexer.TokenMetaTextValue, lexer.TokenMetaStringValue, lexer.TokenMetaBoolValue, lexer.TokenMetaNullValue, lexer.TokenMetaQuantityValue}},
+		{"{{a:12x12}}", lexer.ErrNumberSyntax, "12x", []lexer.TokenType{lexer.TokenMetaNumberValue, lexer.TokenMetaQuantityValue}},
+	}
+
+	for _, test := range tests {
+		l := lexer.Create(test.input)
+		token := lastToken(&l)
+		assert.Equal(t, lexer.TokenError, token.Type, test.input)
+
+		var lexErr *lexer.LexError
+		assert.True(t, errors.As(token.Err, &lexErr), test.input)
+		assert.True(t, errors.Is(lexErr, test.kind), test.input)
+		assert.Equal(t, test.snippet, lexErr.Snippet, test.input)
+		assert.Equal(t, test.expected, lexErr.Expected, test.input)
+		assert.Equal(t, token.Value, lexErr.Message, test.input)
+		assert.Equal(t, token.Start, lexErr.Start, test.input)
+		assert.Same(t, lexErr, l.Err(), test.input)
+	}
+}
+
+func TestErrorKindConfig(t *testing.T) {
+	l := lexer.Create("{{a}}", lexer.WithSeparator(':'))
+	token := lastToken(&l)
+
+	assert.True(t, errors.Is(token.Err, lexer.ErrMetaIndicatorMatch))
+}
+
+func TestErrorKindRead(t *testing.T) {
+	cause := errors.New("disk on fire")
+	l := lexer.CreateFromReader(&failingReader{data: "{{a", err: cause})
+	token := lastToken(&l)
+
+	assert.True(t, errors.Is(token.Err, lexer.ErrRead))
+	assert.True(t, errors.Is(token.Err, cause))
+	assert.False(t, errors.Is(token.Err, lexer.ErrUnclosedMeta))
+}
+
+func TestErrorNoErrOnOtherTokens(t *testing.T) {
+	l := lexer.Create(strings.Repeat("a {{b:1}} ", 3))
+	for token := l.NextToken(); token.Type != lexer.TokenUndefined; token = l.NextToken() {
+		assert.Nil(t, token.Err)
+	}
+}
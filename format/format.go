@@ -0,0 +1,137 @@
+// Package format rewrites the meta blocks of a document in a canonical
+// layout, leaving the plain text around them byte for byte as it is.
+//
+//	formatted, err := format.Format("{{ a : 1 ,b:0X1F }}")
+//	// formatted is {{a: 1, b: 0x1F}}
+package format
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/adroge/lexer"
+)
+
+type config struct {
+	sortKeys bool
+	lexOpts  []lexer.Option
+}
+
+// Option changes how Format lays out meta blocks.
+type Option func(*config)
+
+// WithSortedKeys orders the entries of each meta block by key. Entries
+// with the same key keep their order.
+func WithSortedKeys() Option {
+	return func(c *config) {
+		c.sortKeys = true
+	}
+}
+
+// WithLexerOptions sets the options of the lexer, such as its delimiters.
+func WithLexerOptions(opts ...lexer.Option) Option {
+	return func(c *config) {
+		c.lexOpts = append(c.lexOpts, opts...)
+	}
+}
+
+// entry is a key of a meta block with its formatted value
+type entry struct {
+	key   string
+	value string // empty when the key has no value
+}
+
+// Format returns input with each meta block written on one line, with no
+// space inside the delimiters, the value indicator right after each key
+// followed by a space, and a separator followed by a space between entries:
+//
+//	{{width: 10, title: "Hello", draft}}
+//
+// Numbers are written with a lower case base prefix and exponent and no
+// plus sign, as gofmt writes Go literals. A block holding a comment is
+// kept as written, since moving the comment could change what it is about.
+// Formatting formatted input returns it unchanged.
+//
+// Input with a syntax error is not formatted; the error is returned instead.
+func Format(input string, opts ...Option) (string, error) {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	l := lexer.Create(input, append(slices.Clip(c.lexOpts), lexer.WithLossless())...)
+	_, _, indicator, separator := l.Meta()
+
+	var (
+		output    strings.Builder
+		start     int // offset of the left meta of the block
+		left      string
+		entries   []entry
+		commented bool
+	)
+	for l.Scan() {
+		switch token := l.Token(); token.Type {
+		case lexer.TokenPlainText:
+			output.WriteString(token.Value)
+		case lexer.TokenLeftMeta:
+			start, left, entries, commented = token.Start.Offset, token.Value, nil, false
+		case lexer.TokenMetaIdentifier:
+			entries = append(entries, entry{key: token.Value})
+		case lexer.TokenMetaNumberValue, lexer.TokenMetaQuantityValue:
+			number, unit := token.Quantity()
+			entries[len(entries)-1].value = formatNumber(number) + unit
+		case lexer.TokenMetaTextValue, lexer.TokenMetaStringValue, lexer.TokenMetaBoolValue, lexer.TokenMetaNullValue:
+			entries[len(entries)-1].value = token.Value
+		case lexer.TokenComment:
+			commented = true
+		case lexer.TokenRightMeta:
+			if commented {
+				output.WriteString(input[start:token.End.Offset])
+				continue
+			}
+			if c.sortKeys {
+				slices.SortStableFunc(entries, func(a, b entry) int {
+					return strings.Compare(a.key, b.key)
+				})
+			}
+			output.WriteString(left)
+			for i, entry := range entries {
+				if i > 0 {
+					output.WriteRune(separator)
+					output.WriteByte(' ')
+				}
+				output.WriteString(entry.key)
+				if entry.value != "" {
+					output.WriteRune(indicator)
+					output.WriteByte(' ')
+					output.WriteString(entry.value)
+				}
+			}
+			output.WriteString(token.Value)
+		}
+	}
+	if err := l.Err(); err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+// formatNumber returns number with a lower case base prefix and exponent,
+// and without a plus sign
+func formatNumber(number string) string {
+	number = strings.TrimPrefix(number, "+")
+	sign, digits := "", number
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	if len(digits) > 1 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		hex := digits[1] == 'x' || digits[1] == 'X'
+		digits = "0" + strings.ToLower(digits[1:2]) + digits[2:]
+		if hex {
+			return sign + strings.Replace(digits, "P", "p", 1)
+		}
+		return sign + digits
+	}
+	return sign + strings.Replace(digits, "E", "e", 1)
+}
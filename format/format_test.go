@@ -0,0 +1,69 @@
+package format_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+	"github.com/adroge/lexer/format"
+)
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		"{{a:1,b:2}}":                     "{{a: 1, b: 2}}",
+		"{{ a : 1 , b : 2 }}":             "{{a: 1, b: 2}}",
+		"text {{ x }}  more\t{{y ,, z}} ": "text {{x}}  more\t{{y, z}} ",
+		"{{ }}":                           "{{}}",
+		`{{ s:"a , b" ,t: 'c',u: true}}`:  `{{s: "a , b", t: 'c', u: true}}`,
+		"{{n: +1, h: 0XaB, e: 1E3, p: 0x1P-2, o: 0O17, b: 0B1}}": "{{n: 1, h: 0xaB, e: 1e3, p: 0x1p-2, o: 0o17, b: 0b1}}",
+		"{{q: +1.5E3ms, z: null, d: 1_000}}":                     "{{q: 1.5e3ms, z: null, d: 1_000}}",
+		"{{ a: -0X1F }}":                                         "{{a: -0x1F}}",
+		"no blocks at all":                                       "no blocks at all",
+	}
+	for input, expected := range tests {
+		formatted, err := format.Format(input)
+		assert.Nil(t, err, input)
+		assert.Equal(t, expected, formatted, input)
+
+		again, err := format.Format(formatted)
+		assert.Nil(t, err, input)
+		assert.Equal(t, formatted, again, input)
+	}
+}
+
+func TestFormatSortedKeys(t *testing.T) {
+	formatted, err := format.Format("{{c: 3, a: 1, b, a: 0}}", format.WithSortedKeys())
+	assert.Nil(t, err)
+	assert.Equal(t, "{{a: 1, a: 0, b, c: 3}}", formatted)
+}
+
+func TestFormatLexerOptions(t *testing.T) {
+	formatted, err := format.Format("a \\<< <<  x=1 |y >> b", format.WithLexerOptions(
+		lexer.WithDelimiters("<<", ">>"), lexer.WithValueIndicator('='), lexer.WithSeparator('|'), lexer.WithEscape(`\`)))
+	assert.Nil(t, err)
+	assert.Equal(t, "a \\<< <<x= 1| y>> b", formatted)
+}
+
+func TestFormatMultiline(t *testing.T) {
+	formatted, err := format.Format("{{\n  a: 1\n  b: 2\n}}\n", format.WithLexerOptions(lexer.WithMultiline()))
+	assert.Nil(t, err)
+	assert.Equal(t, "{{a: 1, b: 2}}\n", formatted)
+}
+
+func TestFormatComments(t *testing.T) {
+	input := "{{ a:1 # keep\n}} {{ b:2 }}"
+	formatted, err := format.Format(input, format.WithLexerOptions(lexer.WithMultiline(), lexer.WithLineComment("#")))
+	assert.Nil(t, err)
+	assert.Equal(t, "{{ a:1 # keep\n}} {{b: 2}}", formatted)
+}
+
+func TestFormatError(t *testing.T) {
+	formatted, err := format.Format("{{a: 1}} {{b: *}}")
+	assert.True(t, errors.Is(err, lexer.ErrValueSyntax))
+	assert.Equal(t, "", formatted)
+
+	_, err = format.Format("{{a: *}} {{b}}", format.WithLexerOptions(lexer.WithRecovery()))
+	assert.True(t, errors.Is(err, lexer.ErrValueSyntax))
+}
@@ -0,0 +1,58 @@
+package lexer
+
+import (
+	"context"
+	"iter"
+)
+
+// All lexes input synchronously and returns an iterator over its tokens.
+//
+//	for token, err := range lexer.All(ctx, input) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(token)
+//	}
+func All(ctx context.Context, input string, opts ...Option) iter.Seq2[Token, error] {
+	l := Create(input, opts...)
+	return l.All(ctx)
+}
+
+// All returns an iterator over the remaining tokens. Each token is yielded
+// with a nil error until the end of the input, which is not yielded itself.
+// A syntax error is yielded once, together with its error token, and a done
+// ctx yields its error with an empty token; both end the iteration, except
+// that syntax errors do not with WithRecovery.
+//
+// Without Run, lexing happens as the loop advances, so breaking out of the
+// loop simply stops it, and a later loop carries on from there. After Run,
+// breaking out of the loop or a done ctx stops the lexing goroutine and
+// waits for it, dropping the tokens it has not handed over. Err then
+// reports context.Canceled, unless the input had been lexed to the end.
+func (l *lexer) All(ctx context.Context) iter.Seq2[Token, error] {
+	return func(yield func(Token, error) bool) {
+		for {
+			if err := ctx.Err(); err != nil {
+				if l.tokens != nil {
+					l.stop()
+				} else {
+					l.err = err
+				}
+				yield(Token{}, err)
+				return
+			}
+			if !l.Scan() {
+				break
+			}
+			if token := l.Token(); !yield(token, token.Err) {
+				l.stop()
+				return
+			}
+		}
+		if token := l.Token(); token.Type == TokenError {
+			yield(token, token.Err)
+		} else if l.err != nil {
+			yield(token, l.err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package lexer_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+)
+
+func TestAll(t *testing.T) {
+	var values []string
+	for token, err := range lexer.All(context.Background(), "x{{y:1}}z") {
+		assert.Nil(t, err)
+		values = append(values, token.Value)
+	}
+	assert.Equal(t, []string{"x", "{{", "y", "1", "}}", "z"}, values)
+}
+
+func TestAllError(t *testing.T) {
+	var tokens []lexer.Token
+	var lastErr error
+	for token, err := range lexer.All(context.Background(), "{{z:*}} text") {
+		tokens = append(tokens, token)
+		lastErr = err
+	}
+
+	assert.Equal(t, 3, len(tokens))
+	assert.Equal(t, lexer.TokenError, tokens[2].Type)
+
+	var lexErr *lexer.LexError
+	assert.True(t, errors.As(lastErr, &lexErr))
+}
+
+func TestAllCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	count := 0
+	var lastErr error
+	for _, err := range lexer.All(ctx, "a {{b}} c {{d}} e") {
+		count++
+		lastErr = err
+		cancel()
+	}
+
+	assert.Equal(t, 2, count)
+	assert.True(t, errors.Is(lastErr, context.Canceled))
+}
+
+func TestAllBreak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	l := lexer.Create(strings.Repeat("text {{meta}} ", 100))
+	for token := range l.All(context.Background()) {
+		if token.Type == lexer.TokenMetaIdentifier {
+			break
+		}
+	}
+
+	assert.Equal(t, before, runtime.NumGoroutine())
+
+	// the lexer continues where the loop stopped
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenRightMeta, token.Type)
+}
+
+func TestAllBreakAfterRun(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	l := lexer.Create(strings.Repeat("text {{meta}} ", 100))
+	l.Run(context.Background())
+	for token := range l.All(context.Background()) {
+		if token.Type == lexer.TokenMetaIdentifier {
+			break
+		}
+	}
+	assert.True(t, errors.Is(l.Err(), context.Canceled))
+	assert.Equal(t, lexer.TokenUndefined, l.NextToken().Type)
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestAllCancelledAfterRun(t *testing.T) {
+	l := lexer.Create(strings.Repeat("text {{meta}} ", 100))
+	l.Run(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var last error
+	for token, err := range l.All(ctx) {
+		if token.Type == lexer.TokenMetaIdentifier {
+			cancel()
+		}
+		last = err
+	}
+	assert.True(t, errors.Is(last, context.Canceled))
+	assert.True(t, errors.Is(l.Err(), context.Canceled))
+}
@@ -1,8 +1,12 @@
 package lexer
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"strings"
 	"unicode/utf8"
 )
@@ -10,36 +14,113 @@ import (
 const (
 	_EOF     rune = 0
 	_NEWLINE rune = '\n'
+
+	_READ_SIZE  = 4096           // minimum number of bytes requested from a reader
+	_TEXT_CHUNK = 4 * _READ_SIZE // plain text read from a reader is emitted in tokens of about this size
 )
 
 type lexer struct {
-	input string
+	input  string // input of Create, sliced without copying into token values
+	buffer []byte // input of CreateFromReader; bytes before start are dropped when reading more
+	config config
+	state  stateFn // next state function to run, nil when done
+
+	escapedLeftMeta string // escape followed by the left meta, if escaping
+
+	streaming bool      // input comes from a reader into buffer, so long plain text is emitted in chunks
+	reader    io.Reader // source of more input, nil once exhausted
+	readErr   error     // error returned by reader, other than io.EOF
+	offset    int       // offset of buffer[0] from the beginning of the input
 
 	start int
 	pos   int
 	width int
 
-	tokens chan Token
+	startPosition Position // position of start
+	line          int      // line of pos, 1-based
+	column        int      // column of pos, 1-based
+	prevLine      int      // line before the last call to next, for backup
+	prevColumn    int      // column before the last call to next, for backup
+
+	ctx     context.Context    // set by Run
+	cancel  context.CancelFunc // set by Run, stops the lexing goroutine
+	tokens  chan Token         // set by Run
+	pending []Token            // tokens emitted but not yet returned when not running
+	err     error              // reason the lexer stopped early
+	errs    []error            // errors recovered from
+	token   Token              // last token read by Scan
+}
+
+// Create creates a new lexer. input is the string to be tokenized.
+// The meta values set by SetMeta are used unless changed by opts.
+//
+//	l := lexer.Create(input, lexer.WithDelimiters("<<", ">>"), lexer.WithValueIndicator('='))
+//
+// An invalid configuration is reported as an error token when the lexer runs.
+func Create(input string, opts ...Option) lexer {
+	l := newLexer(opts...)
+	l.input = input
+	return l
 }
 
-// Create creates a new lexer. input is the string to be tokenized
-func Create(input string) lexer {
-	return lexer{
-		input:  input,
-		tokens: make(chan Token, 2),
+// CreateFromReader creates a new lexer that tokenizes everything read from reader.
+// The input is read as it is lexed, and only the text of the token being
+// scanned is kept in memory. So that a long run of plain text is not held
+// whole, it is emitted as several TokenPlainText of about 16 KB each, which
+// never split an escaped left meta. A read error other than io.EOF is
+// reported as an error token.
+func CreateFromReader(reader io.Reader, opts ...Option) lexer {
+	l := newLexer(opts...)
+	l.streaming = true
+	l.reader = reader
+	return l
+}
+
+func newLexer(opts ...Option) lexer {
+	l := lexer{
+		config:        newConfig(opts...),
+		state:         lexText,
+		startPosition: Position{Line: 1, Column: 1},
+		line:          1,
+		column:        1,
+	}
+	if l.config.escape != "" {
+		l.escapedLeftMeta = l.config.escape + l.config.leftMeta
+	}
+	if err := l.config.validate(); err != nil {
+		l.state = func(l *lexer) stateFn {
+			l.errorf(err, nil, "%s", err)
+			return nil // nothing can be lexed, even when recovering
+		}
 	}
+	return l
 }
 
-// Run lexes the input by executing state functions until the state is nil
+// Run lexes the input in a new goroutine by executing state functions
+// until the state is nil, handing tokens to NextToken as they are found.
+// Without Run, NextToken lexes synchronously instead.
+// Calling Run more than once has no effect.
 func (l *lexer) Run(parentCtx context.Context) {
+	if l.tokens != nil {
+		return
+	}
+	l.ctx, l.cancel = context.WithCancel(parentCtx)
+	l.tokens = make(chan Token, 2)
+	pending := l.pending
+	l.pending = nil
+
 	go func() {
 		defer l.finishedRun() // no more new tokens will be delivered upon exit
-		for state := lexText; state != nil; {
+		for _, token := range pending {
+			l.push(token)
+		}
+		for l.state != nil {
 			select {
-			case <-parentCtx.Done():
+			case <-l.ctx.Done():
+				l.err = l.ctx.Err()
 				return
 			default:
-				state = state(l)
+				l.state = l.state(l)
 			}
 		}
 	}()
@@ -48,16 +129,130 @@ func (l *lexer) Run(parentCtx context.Context) {
 // finishedRun closes the chanel and marks the lexer as done.
 func (l *lexer) finishedRun() {
 	close(l.tokens)
+	l.cancel()
+}
+
+// stop makes a running lexer stop lexing, as if the context given to Run
+// was cancelled, and waits for its goroutine to finish, dropping the tokens
+// not read yet. It does nothing when not running.
+func (l *lexer) stop() {
+	if l.tokens == nil {
+		return
+	}
+	l.cancel()
+	for range l.tokens {
+		// the goroutine closes the channel once it is done
+	}
+}
+
+// NextToken returns the next token, and indicates when it is done
+// by returning a token of type TokenUndefined.
+// If Run has not been called, state functions are executed on the
+// calling goroutine until a token is ready.
+func (l *lexer) NextToken() (token Token) {
+	if l.tokens != nil {
+		return <-l.tokens
+	}
+
+	for len(l.pending) == 0 && l.state != nil {
+		l.state = l.state(l)
+	}
+	if len(l.pending) == 0 {
+		return
+	}
+	token = l.pending[0]
+	l.pending = l.pending[1:]
+	return
+}
+
+// Unescape returns the text of a TokenPlainText with each escaped left meta
+// replaced by the left meta alone. Other tokens return their Value.
+func (l *lexer) Unescape(token Token) string {
+	if token.Type != TokenPlainText || l.escapedLeftMeta == "" {
+		return token.Value
+	}
+	return strings.ReplaceAll(token.Value, l.escapedLeftMeta, l.config.leftMeta)
+}
+
+// Bool returns the value of a TokenMetaBoolValue according to the keywords
+// of this lexer. ok is false for other tokens.
+func (l *lexer) Bool(token Token) (value bool, ok bool) {
+	if token.Type != TokenMetaBoolValue {
+		return false, false
+	}
+	return slices.Contains(l.config.trueWords, token.Value), true
+}
+
+// Meta returns the delimiters, value indicator and separator of this lexer,
+// in the order taken by SetMeta.
+func (l *lexer) Meta() (left, right string, valueIndicator, valueSeparator rune) {
+	return l.config.leftMeta, l.config.rightMeta, l.config.valueIndicator, l.config.separator
 }
 
-// NextToken returns the next token, and indicates when it is done.
-func (l *lexer) NextToken() Token {
-	return <-l.tokens
+// Scan advances to the next token, which is then available through Token.
+// It returns false at the end of the input, on a syntax error and when
+// the lexer is cancelled; Err tells these apart.
+//
+//	for l.Scan() {
+//		fmt.Println(l.Token())
+//	}
+//	if err := l.Err(); err != nil {
+//		// handle *LexError or context error
+//	}
+func (l *lexer) Scan() bool {
+	l.token = l.NextToken()
+	switch l.token.Type {
+	case TokenUndefined, TokenEof:
+		return false
+	case TokenError:
+		return l.token.Err.(*LexError).recovered
+	}
+	return true
+}
+
+// Token returns the token read by the last call to Scan.
+func (l *lexer) Token() Token {
+	return l.token
+}
+
+// Err returns the reason the lexer stopped before the end of its input.
+// That is a *LexError for a syntax error, or the context's error when
+// the context given to Run was cancelled. It is nil when the input was
+// lexed to the end, and is only meaningful once Scan has returned false
+// or NextToken has returned TokenError or TokenUndefined.
+// With WithRecovery, the errors recovered from are joined with it.
+func (l *lexer) Err() error {
+	if len(l.errs) == 0 {
+		return l.err
+	}
+	return errors.Join(append(l.errs[:len(l.errs):len(l.errs)], l.err)...)
+}
+
+// push hands a token to NextToken. When running, the token is dropped
+// if the context is done, so a client that stops reading and cancels
+// the context does not leave the lexing goroutine blocked.
+func (l *lexer) push(token Token) {
+	if l.tokens == nil {
+		l.pending = append(l.pending, token)
+		return
+	}
+
+	if err := l.ctx.Err(); err != nil {
+		l.err = err
+		return
+	}
+	select {
+	case l.tokens <- token:
+	case <-l.ctx.Done():
+		l.err = l.ctx.Err()
+	}
 }
 
 // backup steps back one rune and can be called only once per call of next
 func (l *lexer) backup() {
 	l.pos -= l.width
+	l.line = l.prevLine
+	l.column = l.prevColumn
 }
 
 // peek returns but does not consume the next rune in the input
@@ -69,35 +264,174 @@ func (l *lexer) peek() (nextRune rune) {
 
 // next returns the next rune in the input
 func (l *lexer) next() (nextRune rune) {
-	if l.pos >= len(l.input) {
-		l.width = 0
-		return _EOF
+	l.prevLine = l.line
+	l.prevColumn = l.column
+	if !l.streaming {
+		if l.pos >= len(l.input) {
+			l.width = 0
+			return _EOF
+		}
+		nextRune, l.width = utf8.DecodeRuneInString(l.input[l.pos:])
+	} else {
+		if !l.fill(utf8.UTFMax) && l.pos >= len(l.buffer) {
+			l.width = 0
+			return _EOF
+		}
+		nextRune, l.width = utf8.DecodeRune(l.buffer[l.pos:])
 	}
-	nextRune, l.width = utf8.DecodeRuneInString(l.input[l.pos:])
 	l.pos += l.width
+	if nextRune == _NEWLINE {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
 	return
 }
 
+// fill reads from the reader until at least n bytes of buffer follow pos,
+// and reports whether they do. Bytes before start are discarded
+// to make room, so the pending token is never lost.
+func (l *lexer) fill(n int) bool {
+	for len(l.buffer)-l.pos < n {
+		if l.reader == nil {
+			return false
+		}
+
+		if l.start > 0 {
+			pending := copy(l.buffer, l.buffer[l.start:])
+			l.buffer = l.buffer[:pending]
+			l.offset += l.start
+			l.pos -= l.start
+			l.start = 0
+		}
+
+		if cap(l.buffer)-len(l.buffer) < _READ_SIZE {
+			grown := make([]byte, len(l.buffer), 2*cap(l.buffer)+_READ_SIZE)
+			copy(grown, l.buffer)
+			l.buffer = grown
+		}
+
+		read, err := l.reader.Read(l.buffer[len(l.buffer):cap(l.buffer)])
+		l.buffer = l.buffer[:len(l.buffer)+read]
+		if err != nil {
+			if err != io.EOF {
+				l.readErr = err
+			}
+			l.reader = nil
+		}
+	}
+	return true
+}
+
+// hasPrefix reports whether the input at pos starts with prefix
+func (l *lexer) hasPrefix(prefix string) bool {
+	if !l.streaming {
+		return strings.HasPrefix(l.input[l.pos:], prefix)
+	}
+	l.fill(len(prefix))
+	return bytes.HasPrefix(l.buffer[l.pos:], []byte(prefix))
+}
+
+// slice returns the input from offset from to offset to, relative to the
+// start of the buffer when reading. The input of Create is not copied.
+func (l *lexer) slice(from, to int) string {
+	if !l.streaming {
+		return l.input[from:to]
+	}
+	return string(l.buffer[from:to])
+}
+
+// skip consumes the next n bytes of input
+func (l *lexer) skip(n int) {
+	for n > 0 {
+		if l.next(); l.width == 0 {
+			return
+		}
+		n -= l.width
+	}
+}
+
+// position returns the position of pos in the input
+func (l *lexer) position() Position {
+	return Position{Offset: l.offset + l.pos, Line: l.line, Column: l.column}
+}
+
+// reset returns to a position on the current line reached since the last
+// token was emitted, undoing the calls to next in between
+func (l *lexer) reset(position Position) {
+	l.pos = position.Offset - l.offset
+	l.line = position.Line
+	l.column = position.Column
+}
+
 // ignore skips over the pending input before this point
 func (l *lexer) ignore() {
 	l.start = l.pos
+	l.startPosition = l.position()
 }
 
-// emit puts a token onto the token channel
+// emit passes a token back to the client
 func (l *lexer) emit(tokenType TokenType) {
-	l.tokens <- Token{Type: tokenType, Value: l.input[l.start:l.pos]}
-	l.start = l.pos
+	l.push(Token{
+		Type:  tokenType,
+		Value: l.slice(l.start, l.pos),
+		Start: l.startPosition,
+		End:   l.position(),
+	})
+	l.ignore()
+}
+
+// emitTrivia emits text that does not change the meaning of the input,
+// such as a comment or white space, when the client asked for it, and
+// ignores it otherwise
+func (l *lexer) emitTrivia(tokenType TokenType) {
+	if l.config.lossless || tokenType == TokenComment && l.config.commentTokens {
+		l.emit(tokenType)
+		return
+	}
+	l.ignore()
 }
 
 // error returns an error token and terminates the scan
 // by passing back a nil pointer that will be the next
-// state, terminating Lexer.Run
-func (l *lexer) errorf(format string, args ...interface{}) stateFn {
-	l.tokens <- Token{
-		Type:  TokenError,
-		Value: fmt.Sprintf(format, args...),
+// state, terminating Lexer.Run. A failed read is reported
+// in place of the error it caused. With WithRecovery,
+// syntax errors continue the scan with lexRecover instead.
+func (l *lexer) errorf(kind error, expected []TokenType, format string, args ...interface{}) stateFn {
+	err := &LexError{
+		Kind:     kind,
+		Start:    l.startPosition,
+		End:      l.position(),
+		Snippet:  l.slice(l.start, l.pos),
+		Expected: expected,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if l.readErr != nil {
+		err.Kind = ErrRead
+		err.Message = fmt.Sprintf("read error: %s", l.readErr)
+		err.cause = l.readErr
+	}
+	err.recovered = l.config.recover && err.Kind != ErrRead
+	if err.recovered {
+		l.errs = append(l.errs, err)
+	} else {
+		l.err = err
 	}
+	l.push(Token{
+		Type:  TokenError,
+		Value: err.Message,
+		Start: err.Start,
+		End:   err.End,
+		Err:   err,
+	})
 
+	if err.recovered {
+		// the error may have consumed the start of a right or left meta,
+		// so recovery skips from where the error starts
+		l.reset(l.startPosition)
+		return lexRecover
+	}
 	return nil
 }
 
@@ -113,9 +447,8 @@ func (l *lexer) accept(valid string) bool {
 
 // These values are used for acceptRun to determine the type of character that should be accepted.
 const (
-	_Numbers = iota
-	_Hex
-	_Letters
+	_Identifier = iota
+	_MetaSpace
 )
 
 // acceptRun consumes a run of runes from the valid set
@@ -124,12 +457,10 @@ func (l *lexer) acceptRun(acceptType int) {
 	var acceptValidCharacter checkFunc
 
 	switch acceptType {
-	case _Numbers:
-		acceptValidCharacter = isNumber
-	case _Hex:
-		acceptValidCharacter = isHex
-	case _Letters:
-		acceptValidCharacter = isLetter
+	case _Identifier:
+		acceptValidCharacter = l.isIdentifierPart
+	case _MetaSpace:
+		acceptValidCharacter = l.isMetaSpace
 	default:
 		panic("Invalid acceptType detected.")
 	}
@@ -140,3 +471,27 @@ func (l *lexer) acceptRun(acceptType int) {
 
 	l.backup()
 }
+
+// acceptDigits consumes a run of digits of base and '_' separators, and
+// reports whether it found any digits and any separators. For bases up to
+// ten, any decimal digit is consumed, and the first one too large for the
+// base is stored in invalid when it is not nil.
+func (l *lexer) acceptDigits(base int, invalid *rune) (sawDigit, sawSeparator bool) {
+	for {
+		r := l.next()
+		switch {
+		case r == '_':
+			sawSeparator = true
+		case base <= 10 && isNumber(r):
+			sawDigit = true
+			if r >= '0'+rune(base) && invalid != nil && *invalid == 0 {
+				*invalid = r
+			}
+		case base == 16 && isHex(r):
+			sawDigit = true
+		default:
+			l.backup()
+			return
+		}
+	}
+}
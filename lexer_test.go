@@ -2,7 +2,12 @@ package lexer_test
 
 import (
 	"context"
+	"errors"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 
@@ -40,6 +45,46 @@ func TestContextCancel(t *testing.T) {
 	l.NextToken()         // close right meta
 	token = l.NextToken() // undefined - but should be more text if not canceled
 	assert.Equal(t, lexer.TokenUndefined, token.Type)
+	assert.True(t, errors.Is(l.Err(), context.Canceled))
+}
+
+func TestFinishedHasNoErr(t *testing.T) {
+	l := lexer.Create("start text {{meta}}")
+	l.Run(context.Background())
+
+	collect(&l)
+	assert.Nil(t, l.Err())
+}
+
+func TestCancelWithoutReading(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	l := lexer.Create(strings.Repeat("text {{meta}} ", 100))
+	ctx, cancel := context.WithCancel(context.Background())
+	l.Run(ctx)
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenPlainText, token.Type)
+
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestValuesShareInput(t *testing.T) {
+	input := "text {{key: value}}"
+	l := lexer.Create(input)
+
+	for l.Scan() {
+		token := l.Token()
+		if token.Value != "" {
+			assert.Equal(t, unsafe.StringData(input[token.Start.Offset:]), unsafe.StringData(token.Value), token.Value)
+		}
+	}
 }
 
 func TestBasic(t *testing.T) {
@@ -239,7 +284,7 @@ func TestNumericIdentifierValueError(t *testing.T) {
 }
 
 func TestNumericIdentifierValueBadNumber(t *testing.T) {
-	l := lexer.Create("{{a:12e12}}")
+	l := lexer.Create("{{a:12x12}}")
 
 	l.Run(context.Background())
 
@@ -252,7 +297,7 @@ func TestNumericIdentifierValueBadNumber(t *testing.T) {
 
 	token = l.NextToken()
 	assert.Equal(t, lexer.TokenError, token.Type)
-	assert.Equal(t, "number syntax: \"12e\"", token.Value)
+	assert.Equal(t, "number syntax: \"12x\"", token.Value)
 
 	token = l.NextToken()
 	assert.Equal(t, lexer.TokenUndefined, token.Type)
@@ -355,3 +400,459 @@ func TestZeroes(t *testing.T) {
 	token = l.NextToken()
 	assert.Equal(t, lexer.TokenUndefined, token.Type)
 }
+
+func TestTokenPositions(t *testing.T) {
+	l := lexer.Create("ab\ncd{{ x: 5 }}\ný")
+
+	l.Run(context.Background())
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenPlainText, token.Type)
+	assert.Equal(t, lexer.Position{Offset: 0, Line: 1, Column: 1}, token.Start)
+	assert.Equal(t, lexer.Position{Offset: 5, Line: 2, Column: 3}, token.End)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenLeftMeta, token.Type)
+	assert.Equal(t, lexer.Position{Offset: 5, Line: 2, Column: 3}, token.Start)
+	assert.Equal(t, lexer.Position{Offset: 7, Line: 2, Column: 5}, token.End)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenMetaIdentifier, token.Type)
+	assert.Equal(t, lexer.Position{Offset: 8, Line: 2, Column: 6}, token.Start)
+	assert.Equal(t, lexer.Position{Offset: 9, Line: 2, Column: 7}, token.End)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenMetaNumberValue, token.Type)
+	assert.Equal(t, lexer.Position{Offset: 11, Line: 2, Column: 9}, token.Start)
+	assert.Equal(t, lexer.Position{Offset: 12, Line: 2, Column: 10}, token.End)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenRightMeta, token.Type)
+	assert.Equal(t, lexer.Position{Offset: 13, Line: 2, Column: 11}, token.Start)
+	assert.Equal(t, lexer.Position{Offset: 15, Line: 2, Column: 13}, token.End)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenPlainText, token.Type)
+	assert.Equal(t, lexer.Position{Offset: 15, Line: 2, Column: 13}, token.Start)
+	assert.Equal(t, lexer.Position{Offset: 18, Line: 3, Column: 2}, token.End)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenEof, token.Type)
+	assert.Equal(t, lexer.Position{Offset: 18, Line: 3, Column: 2}, token.Start)
+}
+
+func TestErrorPosition(t *testing.T) {
+	l := lexer.Create("line one\n{{a:*}}")
+
+	l.Run(context.Background())
+
+	l.NextToken() // text
+	l.NextToken() // open left meta
+	l.NextToken() // identifier
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenError, token.Type)
+	assert.Equal(t, lexer.Position{Offset: 13, Line: 2, Column: 5}, token.Start)
+	assert.Equal(t, lexer.Position{Offset: 14, Line: 2, Column: 6}, token.End)
+}
+
+func TestSynchronous(t *testing.T) {
+	input := "start {{setting, x:y}} middle {{pi:3.14}} end text."
+
+	concurrent := lexer.Create(input)
+	concurrent.Run(context.Background())
+
+	synchronous := lexer.Create(input)
+
+	assert.Equal(t, collect(&concurrent), collect(&synchronous))
+}
+
+func TestSynchronousError(t *testing.T) {
+	l := lexer.Create("{{z:*}}")
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenLeftMeta, token.Type)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenMetaIdentifier, token.Type)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenError, token.Type)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenUndefined, token.Type)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenUndefined, token.Type)
+}
+
+func TestRunAfterNextToken(t *testing.T) {
+	l := lexer.Create("x{{y}}z")
+
+	token := l.NextToken()
+	assert.Equal(t, "x", token.Value)
+
+	l.Run(context.Background())
+	l.Run(context.Background())
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenLeftMeta, token.Type)
+
+	token = l.NextToken()
+	assert.Equal(t, "y", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenRightMeta, token.Type)
+
+	token = l.NextToken()
+	assert.Equal(t, "z", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenEof, token.Type)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenUndefined, token.Type)
+}
+
+func TestScan(t *testing.T) {
+	l := lexer.Create("x{{y:1}}z")
+
+	var types []lexer.TokenType
+	for l.Scan() {
+		types = append(types, l.Token().Type)
+	}
+
+	assert.Equal(t, []lexer.TokenType{
+		lexer.TokenPlainText,
+		lexer.TokenLeftMeta,
+		lexer.TokenMetaIdentifier,
+		lexer.TokenMetaNumberValue,
+		lexer.TokenRightMeta,
+		lexer.TokenPlainText,
+	}, types)
+	assert.Nil(t, l.Err())
+	assert.False(t, l.Scan())
+	assert.Nil(t, l.Err())
+}
+
+func TestScanError(t *testing.T) {
+	l := lexer.Create("text\n{{z:*}} more")
+	l.Run(context.Background())
+
+	count := 0
+	for l.Scan() {
+		count++
+	}
+	assert.Equal(t, 3, count)
+	assert.Equal(t, lexer.TokenError, l.Token().Type)
+
+	var lexErr *lexer.LexError
+	assert.True(t, errors.As(l.Err(), &lexErr))
+	assert.Equal(t, "value syntax: \"*\"", lexErr.Message)
+	assert.Equal(t, lexer.Position{Offset: 9, Line: 2, Column: 5}, lexErr.Start)
+	assert.Equal(t, lexer.Position{Offset: 10, Line: 2, Column: 6}, lexErr.End)
+	assert.Equal(t, "2:5: value syntax: \"*\"", lexErr.Error())
+}
+
+func TestScanCancel(t *testing.T) {
+	l := lexer.Create(strings.Repeat("text {{meta}} ", 100))
+	ctx, cancel := context.WithCancel(context.Background())
+	l.Run(ctx)
+
+	assert.True(t, l.Scan())
+	cancel()
+
+	for l.Scan() {
+		// drain what was lexed before the cancel was seen
+	}
+	assert.True(t, errors.Is(l.Err(), context.Canceled))
+}
+
+func TestRecovery(t *testing.T) {
+	l := lexer.Create("a {{x:*}} b {{y:1, z:12q3}} c {{ok}}\n{{bad\nd {{12}}", lexer.WithRecovery())
+
+	var types []lexer.TokenType
+	var values []string
+	for l.Scan() {
+		types = append(types, l.Token().Type)
+		values = append(values, l.Token().Value)
+	}
+
+	assert.Equal(t, []lexer.TokenType{
+		lexer.TokenPlainText, lexer.TokenLeftMeta, lexer.TokenMetaIdentifier, lexer.TokenError, lexer.TokenRightMeta,
+		lexer.TokenPlainText, lexer.TokenLeftMeta, lexer.TokenMetaIdentifier, lexer.TokenMetaNumberValue, lexer.TokenMetaIdentifier, lexer.TokenError, lexer.TokenRightMeta,
+		lexer.TokenPlainText, lexer.TokenLeftMeta, lexer.TokenMetaIdentifier, lexer.TokenRightMeta,
+		lexer.TokenPlainText, lexer.TokenLeftMeta, lexer.TokenMetaIdentifier, lexer.TokenError,
+		lexer.TokenPlainText, lexer.TokenLeftMeta, lexer.TokenError, lexer.TokenRightMeta,
+	}, types)
+	assert.Equal(t, "\nd ", values[20])
+	assert.Equal(t, lexer.TokenEof, l.Token().Type)
+
+	err := l.Err()
+	assert.True(t, errors.Is(err, lexer.ErrValueSyntax))
+	assert.True(t, errors.Is(err, lexer.ErrNumberSyntax))
+	assert.True(t, errors.Is(err, lexer.ErrUnclosedMeta))
+	assert.True(t, errors.Is(err, lexer.ErrIdentifierSyntax))
+	assert.Equal(t, 4, strings.Count(err.Error(), "\n")+1)
+
+	// an error that consumed the right meta or ran into a left meta
+	// does not hide the text and the blocks that follow
+	tests := map[string][]string{
+		"{{a:}} more text {{b:*}} end": {"{{", "a", `value syntax: "}"`, "}}", " more text ", "{{", "b", `value syntax: "*"`, "}}", " end"},
+		"{{a.}} more text {{b}} end":   {"{{", `identifier syntax: "a.}"`, "}}", " more text ", "{{", "b", "}}", " end"},
+		"{{a:* more {{b}} end":         {"{{", "a", `value syntax: "*"`, "{{", "b", "}}", " end"},
+	}
+	for input, expected := range tests {
+		var values []string
+		for token := range lexer.All(context.Background(), input, lexer.WithRecovery()) {
+			values = append(values, token.Value)
+		}
+		assert.Equal(t, expected, values, input)
+	}
+}
+
+func TestRecoveryReadError(t *testing.T) {
+	l := lexer.CreateFromReader(&failingReader{data: "{{a:*}} {{b", err: errors.New("gone")}, lexer.WithRecovery())
+
+	for l.Scan() {
+		// errors are checked at the end
+	}
+
+	assert.True(t, errors.Is(l.Err(), lexer.ErrValueSyntax))
+	assert.True(t, errors.Is(l.Err(), lexer.ErrRead))
+}
+
+func TestRecoveryAll(t *testing.T) {
+	var errs []error
+	for _, err := range lexer.All(context.Background(), "{{a:*}} {{b:?}} {{c}}", lexer.WithRecovery()) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	assert.Equal(t, 2, len(errs))
+}
+
+func TestStringValues(t *testing.T) {
+	l := lexer.Create(`{{title: "Hello, World}}", path: '/var/log', msg: "it's \"done\"", raw: ` + "`C:\\dir`" + `}}`)
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenLeftMeta, token.Type)
+
+	expected := []struct {
+		identifier string
+		raw        string
+		unquoted   string
+	}{
+		{"title", `"Hello, World}}"`, "Hello, World}}"},
+		{"path", `'/var/log'`, "/var/log"},
+		{"msg", `"it's \"done\""`, `it's "done"`},
+		{"raw", "`C:\\dir`", `C:\dir`},
+	}
+	for _, e := range expected {
+		token = l.NextToken()
+		assert.Equal(t, lexer.TokenMetaIdentifier, token.Type)
+		assert.Equal(t, e.identifier, token.Value)
+
+		token = l.NextToken()
+		assert.Equal(t, lexer.TokenMetaStringValue, token.Type)
+		assert.Equal(t, e.raw, token.Value)
+		unquoted, err := token.Unquote()
+		assert.Nil(t, err)
+		assert.Equal(t, e.unquoted, unquoted)
+	}
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenRightMeta, token.Type)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenEof, token.Type)
+}
+
+func TestStringValueErrors(t *testing.T) {
+	tests := map[string]string{
+		`{{a: "open}}`:      `string syntax: "\"open}}"`,
+		"{{a: 'line\n'}}":   `string syntax: "'line"`,
+		`{{a: "bad \q"}}`:   `string syntax: "\"bad \\q\""`,
+		`{{a: "\`:           `string syntax: "\"\\"`,
+		"{{a: `no end}}":    "string syntax: \"`no end}}\"",
+		`{{a: 'it\"s'}}`:    `string syntax: "'it\\\"s'"`,
+		`{{a: "\u00e9"}}`:   "",
+		`{{a: "\xff\101"}}`: "",
+	}
+
+	for input, message := range tests {
+		l := lexer.Create(input)
+		token := lastToken(&l)
+		if message == "" {
+			assert.Equal(t, lexer.TokenEof, token.Type, input)
+			continue
+		}
+		assert.Equal(t, lexer.TokenError, token.Type, input)
+		assert.Equal(t, message, token.Value, input)
+		assert.True(t, errors.Is(token.Err, lexer.ErrStringSyntax), input)
+	}
+}
+
+func TestIdentifierGrammar(t *testing.T) {
+	l := lexer.Create("{{h1, max_width: 10, data-id: x_1, _private, page.title: a-b, a.b_2.c}}")
+
+	var tokens []lexer.Token
+	for l.Scan() {
+		tokens = append(tokens, l.Token())
+	}
+	assert.Nil(t, l.Err())
+
+	var values []string
+	for _, token := range tokens {
+		values = append(values, token.Value)
+	}
+	assert.Equal(t, []string{"{{", "h1", "max_width", "10", "data-id", "x_1", "_private", "page.title", "a-b", "a.b_2.c", "}}"}, values)
+
+	assert.Equal(t, lexer.TokenMetaIdentifier, tokens[7].Type)
+	assert.Equal(t, []string{"page", "title"}, tokens[7].Path())
+	assert.Equal(t, lexer.TokenMetaTextValue, tokens[8].Type)
+	assert.Equal(t, []string{"a", "b_2", "c"}, tokens[9].Path())
+}
+
+func TestIdentifierPathErrors(t *testing.T) {
+	tests := map[string]string{
+		"{{a.}}":   `identifier syntax: "a.}"`,
+		"{{a..b}}": `identifier syntax: "a.."`,
+		"{{a.1}}":  `identifier syntax: "a.1"`,
+		"{{-a}}":   `identifier syntax: "-"`,
+	}
+
+	for input, message := range tests {
+		l := lexer.Create(input)
+		token := lastToken(&l)
+		assert.Equal(t, lexer.TokenError, token.Type, input)
+		assert.Equal(t, message, token.Value, input)
+	}
+}
+
+func TestBoolAndNullValues(t *testing.T) {
+	l := lexer.Create(`{{enabled: true, visible: false, parent: null, name: "true", label: truely}}`)
+
+	var tokens []lexer.Token
+	for l.Scan() {
+		if l.Token().Type != lexer.TokenMetaIdentifier {
+			tokens = append(tokens, l.Token())
+		}
+	}
+	assert.Nil(t, l.Err())
+
+	assert.Equal(t, lexer.TokenMetaBoolValue, tokens[1].Type)
+	value, ok := l.Bool(tokens[1])
+	assert.True(t, ok)
+	assert.True(t, value)
+
+	assert.Equal(t, lexer.TokenMetaBoolValue, tokens[2].Type)
+	value, ok = l.Bool(tokens[2])
+	assert.True(t, ok)
+	assert.False(t, value)
+
+	assert.Equal(t, lexer.TokenMetaNullValue, tokens[3].Type)
+	assert.Equal(t, lexer.TokenMetaStringValue, tokens[4].Type)
+	assert.Equal(t, lexer.TokenMetaTextValue, tokens[5].Type)
+
+	_, ok = l.Bool(tokens[4])
+	assert.False(t, ok)
+}
+
+func TestNumberGrammar(t *testing.T) {
+	valid := []string{
+		"0", "00", "017", "42", "+42", "-42", "1_000_000", "0x2a", "0X2A", "0x_2a", "0o52", "0O5_2",
+		"0b101010", "0b_1010", "4.2", "1.", "-.5", "4.2e1", "1e9", "1E-9", "1e+9", "0x1p-2",
+		"0x1.8p1", "0x.8p0", "09.5", "0e0", "1_0.2_5e1_0", "0x1e3p1", ".5", ".5e1",
+	}
+	for _, number := range valid {
+		l := lexer.Create("{{a: " + number + "}}")
+		var tokens []lexer.Token
+		for l.Scan() {
+			tokens = append(tokens, l.Token())
+		}
+		assert.Nil(t, l.Err(), number)
+		if assert.Equal(t, 4, len(tokens), number) {
+			assert.Equal(t, lexer.TokenMetaNumberValue, tokens[2].Type, number)
+			assert.Equal(t, number, tokens[2].Value, number)
+		}
+	}
+}
+
+func TestNumberGrammarErrors(t *testing.T) {
+	tests := map[string]string{
+		"0x":       `number syntax: "0x": hexadecimal literal has no digits`,
+		"0b":       `number syntax: "0b": binary literal has no digits`,
+		"+":        `number syntax: "+": decimal literal has no digits`,
+		"+-1":      `number syntax: "+": decimal literal has no digits`,
+		"0x1.f":    `number syntax: "0x1.f": hexadecimal mantissa requires a 'p' exponent`,
+		"0b1.1":    `number syntax: "0b1.": invalid radix point in binary literal`,
+		"0o8":      `number syntax: "0o8": invalid digit '8' in octal literal`,
+		"09":       `number syntax: "09": invalid digit '9' in octal literal`,
+		"0b102":    `number syntax: "0b102": invalid digit '2' in binary literal`,
+		"1e":       `number syntax: "1e": exponent has no digits`,
+		"1e+":      `number syntax: "1e+": exponent has no digits`,
+		"1p3":      `number syntax: "1p": 'p' exponent requires hexadecimal mantissa`,
+		"0b1e3":    `number syntax: "0b1e": 'e' exponent requires decimal mantissa`,
+		"1__000":   `number syntax: "1__000": '_' must separate successive digits`,
+		"1_":       `number syntax: "1_": '_' must separate successive digits`,
+		"1_.5":     `number syntax: "1_.5": '_' must separate successive digits`,
+		"0_x1":     `number syntax: "0_": '_' must separate successive digits`,
+		"12abc":    `number syntax: "12a"`,
+		"1.5e3_":   `number syntax: "1.5e3_": '_' must separate successive digits`,
+		"0x_":      `number syntax: "0x_": hexadecimal literal has no digits`,
+		"0x1p1_0_": `number syntax: "0x1p1_0_": '_' must separate successive digits`,
+		"1.5.5":    `number syntax: "1.5.5": unexpected radix point`,
+		"1..5":     `number syntax: "1..5": unexpected radix point`,
+		"1e3.5":    `number syntax: "1e3.5": unexpected radix point`,
+		"-.5.":     `number syntax: "-.5.": unexpected radix point`,
+	}
+
+	for number, message := range tests {
+		l := lexer.Create("{{a: "+number+"}}", lexer.WithUnits())
+		token := lastToken(&l)
+		assert.Equal(t, lexer.TokenError, token.Type, number)
+		assert.Equal(t, message, token.Value, number)
+		assert.True(t, errors.Is(token.Err, lexer.ErrNumberSyntax), number)
+	}
+}
+
+func TestQuantityValues(t *testing.T) {
+	l := lexer.Create("{{timeout: 30s, size: 10MB, opacity: 50%, wait: 1.5h, big: 5EB, disk: 2GiB, zero: 0s, exp: 1e3ms}}",
+		lexer.WithUnits(append(lexer.DefaultUnits, "EB")...))
+
+	var values []string
+	for l.Scan() {
+		if token := l.Token(); token.Type == lexer.TokenMetaQuantityValue {
+			values = append(values, token.Value)
+		}
+	}
+	assert.Nil(t, l.Err())
+	assert.Equal(t, []string{"30s", "10MB", "50%", "1.5h", "5EB", "2GiB", "0s", "1e3ms"}, values)
+}
+
+func TestQuantityErrors(t *testing.T) {
+	tests := map[string]string{
+		"{{a: 10parsecs}}": `number syntax: "10parsecs"`,
+		"{{a: 1e}}":        `number syntax: "1e"`,
+		"{{a: 0x10s}}":     `number syntax: "0x10s"`,
+		"{{a: 10s5}}":      `identifier syntax: "5"`,
+	}
+
+	for input, message := range tests {
+		l := lexer.Create(input)
+		token := lastToken(&l)
+		assert.Equal(t, lexer.TokenError, token.Type, input)
+		assert.Equal(t, message, token.Value, input)
+	}
+
+	l := lexer.Create("{{a: 10px}}", lexer.WithUnits())
+	token := lastToken(&l)
+	assert.Equal(t, `number syntax: "10p": 'p' exponent requires hexadecimal mantissa`, token.Value)
+
+	l = lexer.Create("{{a: 12px, b: 1.5em}}", lexer.WithUnits("px", "em"))
+	for l.Scan() {
+		// only errors matter
+	}
+	assert.Nil(t, l.Err())
+}
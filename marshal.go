@@ -0,0 +1,209 @@
+package lexer
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+// durationOrder lists the units of durationUnits from the largest
+var durationOrder = []string{"h", "m", "s", "ms", "us", "µs", "μs", "ns"}
+
+// Marshal returns a meta block holding the entries of v, written with the
+// delimiters, value indicator and separator of opts. v is a struct, whose
+// fields are written in order under the keys of their meta tags, or a map
+// with string keys, whose entries are written in the order of their keys.
+// A field whose tag has the omitempty option is left out when it is the
+// zero value.
+//
+//	text, err := lexer.Marshal(struct {
+//		Width int    `meta:"width"`
+//		Title string `meta:"title"`
+//	}{10, "Hello, world"})
+//	// text is {{width: 10, title: "Hello, world"}}
+//
+// Text is written bare when it would be lexed back as a TokenMetaTextValue,
+// and quoted otherwise. Bool and nil values are written with the first of
+// their keywords, and a time.Duration with the largest unit that keeps it
+// whole. A slice is written as its key repeated for each element, and a
+// value implementing encoding.TextMarshaler as its text. Unmarshal reads
+// the result back, provided that the fields of slices that may be empty
+// have the optional option, as an empty slice writes no entry.
+func Marshal(v any, opts ...Option) (string, error) {
+	l := newLexer(opts...)
+	if err := l.config.validate(); err != nil {
+		return "", err
+	}
+
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+
+	var (
+		entries []string
+		err     error
+	)
+	switch value.Kind() {
+	case reflect.Struct:
+		for _, field := range metaFields(value.Type()) {
+			fieldValue, fieldErr := value.FieldByIndexErr(field.index)
+			if fieldErr != nil || field.omitEmpty && fieldValue.IsZero() {
+				continue // in a nil embedded struct, or empty
+			}
+			if entries, err = l.appendEntries(entries, field.key, fieldValue); err != nil {
+				return "", err
+			}
+		}
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return "", fmt.Errorf("lexer: cannot marshal %s, need a struct or a map with string keys", value.Type())
+		}
+		keys := value.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(a.String(), b.String())
+		})
+		for _, key := range keys {
+			if entries, err = l.appendEntries(entries, key.String(), value.MapIndex(key)); err != nil {
+				return "", err
+			}
+		}
+	default:
+		return "", fmt.Errorf("lexer: cannot marshal %T, need a struct or a map with string keys", v)
+	}
+
+	return l.config.leftMeta + strings.Join(entries, string(l.config.separator)+" ") + l.config.rightMeta, nil
+}
+
+// appendEntries appends the entries of key and value, one for each element
+// of a slice, to entries
+func (l *lexer) appendEntries(entries []string, key string, value reflect.Value) ([]string, error) {
+	for _, part := range strings.Split(key, ".") {
+		if !l.isWord(part) {
+			return nil, fmt.Errorf("lexer: key %q is not an identifier", key)
+		}
+	}
+	entry := key + string(l.config.valueIndicator) + " "
+
+	if value.Kind() == reflect.Interface && !value.IsNil() {
+		value = value.Elem()
+	}
+	if kind := value.Kind(); (kind == reflect.Slice || kind == reflect.Array) && !value.Type().Implements(textMarshalerType) {
+		for i := range value.Len() {
+			text, err := l.marshalValue(value.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("lexer: key %q: %w", key, err)
+			}
+			entries = append(entries, entry+text)
+		}
+		return entries, nil
+	}
+
+	text, err := l.marshalValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("lexer: key %q: %w", key, err)
+	}
+	return append(entries, entry+text), nil
+}
+
+// marshalValue returns value as it is written in a meta block
+func (l *lexer) marshalValue(value reflect.Value) (string, error) {
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if value.IsNil() {
+			return l.keyword(l.config.nullWords, "null")
+		}
+		return l.marshalValue(value.Elem())
+	}
+
+	if value.Type() == durationType {
+		return l.duration(time.Duration(value.Int()))
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(textMarshalerType) {
+		value = value.Addr()
+	}
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return l.text(string(text)), nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return l.text(value.String()), nil
+	case reflect.Bool:
+		if value.Bool() {
+			return l.keyword(l.config.trueWords, "true")
+		}
+		return l.keyword(l.config.falseWords, "false")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(value.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		float := value.Float()
+		if math.IsNaN(float) || math.IsInf(float, 0) {
+			return "", fmt.Errorf("unsupported value %v", float)
+		}
+		return strconv.FormatFloat(float, 'g', -1, value.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", value.Type())
+}
+
+// keyword returns the first of words, which are the keywords for kind
+func (l *lexer) keyword(words []string, kind string) (string, error) {
+	if len(words) == 0 {
+		return "", fmt.Errorf("no %s keyword", kind)
+	}
+	return words[0], nil
+}
+
+// duration returns duration with the largest unit that keeps it whole,
+// and zero in seconds when it can
+func (l *lexer) duration(duration time.Duration) (string, error) {
+	if duration == 0 && slices.Contains(l.config.units, "s") {
+		return "0s", nil
+	}
+	for _, unit := range durationOrder {
+		if duration%durationUnits[unit] == 0 && slices.Contains(l.config.units, unit) {
+			return fmt.Sprintf("%d%s", duration/durationUnits[unit], unit), nil
+		}
+	}
+	return "", fmt.Errorf("no unit for duration %s", duration)
+}
+
+// text returns text bare when it is lexed as a TokenMetaTextValue,
+// and quoted otherwise
+func (l *lexer) text(text string) string {
+	if !l.isWord(text) ||
+		slices.Contains(l.config.trueWords, text) ||
+		slices.Contains(l.config.falseWords, text) ||
+		slices.Contains(l.config.nullWords, text) {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+// isWord reports whether text is a single identifier, or a text value
+func (l *lexer) isWord(text string) bool {
+	first, _ := utf8.DecodeRuneInString(text)
+	if !l.isIdentifierStart(first) {
+		return false
+	}
+	for _, r := range text {
+		if !l.isIdentifierPart(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package lexer_test
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+)
+
+func TestMarshal(t *testing.T) {
+	author := "ann"
+	text, err := lexer.Marshal(&page{
+		Title:   "Home page",
+		Width:   10,
+		Ratio:   1.5,
+		Draft:   true,
+		Delay:   90 * time.Second,
+		Tags:    []string{"a", "null"},
+		Author:  &author,
+		Address: netip.MustParseAddr("10.0.0.1"),
+		Ignored: "not written",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `{{title: "Home page", width: 10, ratio: 1.5, draft: true, public: false, delay: 90s, tag: a, tag: "null", page.author: ann, address: "10.0.0.1"}}`, text)
+
+	var p page
+	assert.Nil(t, lexer.Unmarshal(text, &p))
+	assert.Equal(t, "Home page", p.Title)
+	assert.Equal(t, 90*time.Second, p.Delay)
+	assert.Equal(t, []string{"a", "null"}, p.Tags)
+	assert.Equal(t, "ann", *p.Author)
+}
+
+func TestMarshalEmptySlice(t *testing.T) {
+	type list struct {
+		Name  string   `meta:"name"`
+		Items []string `meta:"item,optional"`
+	}
+	text, err := lexer.Marshal(list{Name: "empty"})
+	assert.Nil(t, err)
+	assert.Equal(t, "{{name: empty}}", text)
+
+	var back list
+	assert.Nil(t, lexer.Unmarshal(text, &back))
+	assert.Equal(t, list{Name: "empty"}, back)
+
+	// without the optional option, the missing key is reported
+	var required struct {
+		Name  string   `meta:"name"`
+		Items []string `meta:"item"`
+	}
+	assert.True(t, errors.Is(lexer.Unmarshal(text, &required), lexer.ErrMissingKey))
+}
+
+func TestMarshalMap(t *testing.T) {
+	text, err := lexer.Marshal(map[string]any{
+		"width": 10,
+		"title": "Hi",
+		"none":  nil,
+		"big":   1e21,
+		"tags":  []any{"x", 2},
+		"wait":  time.Duration(0),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `{{big: 1e+21, none: null, tags: x, tags: 2, title: Hi, wait: 0s, width: 10}}`, text)
+
+	doc, err := lexer.Marshal(map[string]int{})
+	assert.Nil(t, err)
+	assert.Equal(t, "{{}}", doc)
+}
+
+func TestMarshalOptions(t *testing.T) {
+	value := struct {
+		Width int           `meta:"width"`
+		Show  bool          `meta:"show"`
+		Wait  time.Duration `meta:"wait"`
+		Note  string        `meta:"note,omitempty"`
+	}{Width: 3, Show: true, Wait: 1500 * time.Millisecond}
+
+	text, err := lexer.Marshal(value, lexer.WithDelimiters("<<", ">>"), lexer.WithValueIndicator('='),
+		lexer.WithSeparator('|'), lexer.WithBoolKeywords([]string{"yes"}, []string{"no"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "<<width= 3| show= yes| wait= 1500ms>>", text)
+
+	_, err = lexer.Marshal(value, lexer.WithUnits())
+	assert.EqualError(t, err, `lexer: key "wait": no unit for duration 1.5s`)
+
+	_, err = lexer.Marshal(value, lexer.WithSeparator(':'))
+	assert.ErrorIs(t, err, lexer.ErrMetaIndicatorMatch)
+}
+
+func TestMarshalText(t *testing.T) {
+	tests := map[string]string{
+		"word":      "word",
+		"two words": `"two words"`,
+		"x-1_y":     "x-1_y",
+		"1x":        `"1x"`,
+		"":          `""`,
+		"true":      `"true"`,
+		"a\nb":      `"a\nb"`,
+		"héllo":     `"héllo"`,
+	}
+	for value, expected := range tests {
+		text, err := lexer.Marshal(map[string]string{"k": value})
+		assert.Nil(t, err, value)
+		assert.Equal(t, "{{k: "+expected+"}}", text, value)
+	}
+
+	text, err := lexer.Marshal(map[string]string{"k": "héllo"}, lexer.WithUnicode())
+	assert.Nil(t, err)
+	assert.Equal(t, "{{k: héllo}}", text)
+}
+
+func TestMarshalErrors(t *testing.T) {
+	_, err := lexer.Marshal(42)
+	assert.EqualError(t, err, "lexer: cannot marshal int, need a struct or a map with string keys")
+
+	_, err = lexer.Marshal(map[int]string{1: "a"})
+	assert.EqualError(t, err, "lexer: cannot marshal map[int]string, need a struct or a map with string keys")
+
+	_, err = lexer.Marshal(map[string]any{"bad key": 1})
+	assert.EqualError(t, err, `lexer: key "bad key" is not an identifier`)
+
+	_, err = lexer.Marshal(map[string]any{"k": struct{}{}})
+	assert.EqualError(t, err, `lexer: key "k": unsupported type struct {}`)
+}
@@ -0,0 +1,219 @@
+package lexer
+
+import "sync"
+
+// config holds the settings a lexer uses while scanning its input.
+type config struct {
+	leftMeta       string
+	rightMeta      string
+	valueIndicator rune
+	separator      rune
+	recover        bool
+	unicode        bool
+	multiline      bool
+	escape         string
+
+	lineComment       string
+	blockCommentOpen  string
+	blockCommentClose string
+	commentTokens     bool
+	lossless          bool
+
+	trueWords  []string
+	falseWords []string
+	nullWords  []string
+
+	units []string
+}
+
+// Option customizes a single lexer when passed to Create.
+type Option func(*config)
+
+// DefaultUnits are the units a number may be followed by unless
+// changed with WithUnits: durations, byte sizes and percentages.
+var DefaultUnits = []string{
+	"ns", "us", "µs", "μs", "ms", "s", "m", "h",
+	"B", "KB", "MB", "GB", "TB", "PB", "KiB", "MiB", "GiB", "TiB", "PiB",
+	"%",
+}
+
+var (
+	defaultConfigLock sync.RWMutex
+	defaultConfig     = config{
+		leftMeta:       "{{",
+		rightMeta:      "}}",
+		valueIndicator: ':',
+		separator:      ',',
+		trueWords:      []string{"true"},
+		falseWords:     []string{"false"},
+		nullWords:      []string{"null"},
+		units:          DefaultUnits,
+	}
+)
+
+// WithDelimiters sets the left and right meta delimiters.
+//
+//	l := lexer.Create(input, lexer.WithDelimiters("<<", ">>"))
+func WithDelimiters(left, right string) Option {
+	return func(c *config) {
+		c.leftMeta = left
+		c.rightMeta = right
+	}
+}
+
+// WithValueIndicator sets the rune between an identifier and its value.
+func WithValueIndicator(indicator rune) Option {
+	return func(c *config) {
+		c.valueIndicator = indicator
+	}
+}
+
+// WithSeparator sets the rune between identifiers.
+func WithSeparator(separator rune) Option {
+	return func(c *config) {
+		c.separator = separator
+	}
+}
+
+// WithRecovery makes the lexer continue after a syntax error. The error token
+// is emitted, the rest of the bad meta block is skipped up to its right meta,
+// the next left meta or the end of the line, and lexing carries on from there.
+// Scan does not stop on error tokens, and Err returns every error found.
+func WithRecovery() Option {
+	return func(c *config) {
+		c.recover = true
+	}
+}
+
+// WithUnicode lets identifiers and text values use any Unicode letter,
+// and continue with Unicode digits and combining marks, in the style of
+// UAX #31. Without it only the ASCII letters are accepted.
+//
+//	l := lexer.Create("{{größe: groß}}", lexer.WithUnicode())
+func WithUnicode() Option {
+	return func(c *config) {
+		c.unicode = true
+	}
+}
+
+// WithMultiline lets meta blocks span lines by treating line endings,
+// both \n and \r\n, as white space inside the meta tags. A block that is
+// still open at the end of the input remains an error.
+func WithMultiline() Option {
+	return func(c *config) {
+		c.multiline = true
+	}
+}
+
+// WithEscape makes a left meta preceded by escape part of the plain text.
+// The escape can be a rune such as \ or the left meta itself, so that
+// \{{ or {{{{ stands for a literal {{. The plain text token keeps the
+// escape, and Unescape removes it.
+//
+//	l := lexer.Create(`write \{{name}} for a name`, lexer.WithEscape(`\`))
+func WithEscape(escape string) Option {
+	return func(c *config) {
+		c.escape = escape
+	}
+}
+
+// WithLineComment lets a comment start with prefix inside the meta tags.
+// It runs up to the right meta or the end of the line.
+//
+//	l := lexer.Create("{{ width: 10 # pixels }}", lexer.WithLineComment("#"))
+func WithLineComment(prefix string) Option {
+	return func(c *config) {
+		c.lineComment = prefix
+	}
+}
+
+// WithBlockComment lets a comment be written between open and close
+// inside the meta tags.
+//
+//	l := lexer.Create("{{/* note to editors */}}", lexer.WithBlockComment("/*", "*/"))
+func WithBlockComment(open, close string) Option {
+	return func(c *config) {
+		c.blockCommentOpen = open
+		c.blockCommentClose = close
+	}
+}
+
+// WithCommentTokens emits comments as TokenComment instead of skipping them.
+func WithCommentTokens() Option {
+	return func(c *config) {
+		c.commentTokens = true
+	}
+}
+
+// WithLossless emits the text that other tokens leave out inside the meta
+// tags: white space as TokenWhitespace, separators as TokenSeparator, value
+// indicators as TokenValueIndicator and comments as TokenComment. The Values
+// of the tokens of input without syntax errors then add up to the input.
+//
+//	var text strings.Builder
+//	for token, _ := range lexer.All(ctx, input, lexer.WithLossless()) {
+//		text.WriteString(token.Value)
+//	}
+func WithLossless() Option {
+	return func(c *config) {
+		c.lossless = true
+	}
+}
+
+// WithBoolKeywords sets the bare values lexed as TokenMetaBoolValue,
+// replacing true and false.
+//
+//	l := lexer.Create(input, lexer.WithBoolKeywords([]string{"true", "yes", "on"}, []string{"false", "no", "off"}))
+func WithBoolKeywords(truthy, falsy []string) Option {
+	return func(c *config) {
+		c.trueWords = truthy
+		c.falseWords = falsy
+	}
+}
+
+// WithNullKeywords sets the bare values lexed as TokenMetaNullValue,
+// replacing null.
+func WithNullKeywords(words ...string) Option {
+	return func(c *config) {
+		c.nullWords = words
+	}
+}
+
+// WithUnits sets the units a decimal number may be followed by to form
+// a TokenMetaQuantityValue, replacing DefaultUnits. A unit is made of
+// letters and %. Without units, a number cannot be followed by a letter.
+//
+//	l := lexer.Create("{{width: 12px}}", lexer.WithUnits("px", "em", "%"))
+func WithUnits(units ...string) Option {
+	return func(c *config) {
+		c.units = units
+	}
+}
+
+// newConfig starts from the process default set by SetMeta and applies opts.
+func newConfig(opts ...Option) config {
+	defaultConfigLock.RLock()
+	c := defaultConfig
+	defaultConfigLock.RUnlock()
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func (c config) validate() error {
+	if len(c.leftMeta) == 0 || len(c.rightMeta) == 0 {
+		return ErrMetaZeroLength
+	}
+
+	if c.valueIndicator == c.separator {
+		return ErrMetaIndicatorMatch
+	}
+
+	if (len(c.blockCommentOpen) == 0) != (len(c.blockCommentClose) == 0) {
+		return ErrCommentZeroLength
+	}
+
+	return nil
+}
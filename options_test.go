@@ -0,0 +1,350 @@
+package lexer_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+)
+
+func TestOptionsOverrideDefaults(t *testing.T) {
+	l := lexer.Create("text <<a=5|b=abc>> {{end}}",
+		lexer.WithDelimiters("<<", ">>"),
+		lexer.WithValueIndicator('='),
+		lexer.WithSeparator('|'),
+	)
+	l.Run(context.Background())
+
+	token := l.NextToken()
+	assert.Equal(t, "text ", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenLeftMeta, token.Type)
+	assert.Equal(t, "<<", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenMetaIdentifier, token.Type)
+	assert.Equal(t, "a", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenMetaNumberValue, token.Type)
+	assert.Equal(t, "5", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenMetaIdentifier, token.Type)
+	assert.Equal(t, "b", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenMetaTextValue, token.Type)
+	assert.Equal(t, "abc", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenRightMeta, token.Type)
+	assert.Equal(t, ">>", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenPlainText, token.Type)
+	assert.Equal(t, " {{end}}", token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenEof, token.Type)
+}
+
+func TestOptionsInvalid(t *testing.T) {
+	l := lexer.Create("{{a}}", lexer.WithDelimiters("", "}}"))
+	l.Run(context.Background())
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenError, token.Type)
+	assert.Equal(t, lexer.ErrMetaZeroLength.Error(), token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenUndefined, token.Type)
+}
+
+func TestOptionsIndicatorMatch(t *testing.T) {
+	l := lexer.Create("{{a}}", lexer.WithSeparator(':'))
+	l.Run(context.Background())
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenError, token.Type)
+	assert.Equal(t, lexer.ErrMetaIndicatorMatch.Error(), token.Value)
+}
+
+func TestOptionsConcurrentLexers(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			l := lexer.Create("<<a>>", lexer.WithDelimiters("<<", ">>"))
+			l.Run(context.Background())
+			assert.Equal(t, "<<", l.NextToken().Value)
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, lexer.SetMeta("{{", "}}", ':', ','))
+			l := lexer.Create("{{a}}")
+			l.Run(context.Background())
+			assert.Equal(t, "{{", l.NextToken().Value)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOptionsUnicode(t *testing.T) {
+	l := lexer.Create("{{größe: groß, タイトル: 本, नमस्ते: ok2}}", lexer.WithUnicode())
+
+	var values []string
+	for l.Scan() {
+		values = append(values, l.Token().Value)
+	}
+
+	assert.Nil(t, l.Err())
+	assert.Equal(t, []string{"{{", "größe", "groß", "タイトル", "本", "नमस्ते", "ok2", "}}"}, values)
+}
+
+func TestOptionsUnicodeNumberSuffix(t *testing.T) {
+	l := lexer.Create("{{a: 12é}}", lexer.WithUnicode())
+	token := lastToken(&l)
+
+	assert.Equal(t, lexer.TokenError, token.Type)
+	assert.Equal(t, "number syntax: \"12é\"", token.Value)
+}
+
+func TestOptionsWithoutUnicode(t *testing.T) {
+	l := lexer.Create("{{größe}}")
+	token := lastToken(&l)
+
+	assert.Equal(t, lexer.TokenError, token.Type)
+	assert.Equal(t, "identifier syntax: \"ö\"", token.Value)
+}
+
+func TestOptionsMultiline(t *testing.T) {
+	l := lexer.Create("a {{\n  width: 10,\r\n  height:\n 20\n}} b", lexer.WithMultiline())
+
+	var values []string
+	for l.Scan() {
+		values = append(values, l.Token().Value)
+	}
+
+	assert.Nil(t, l.Err())
+	assert.Equal(t, []string{"a ", "{{", "width", "10", "height", "20", "}}", " b"}, values)
+}
+
+func TestOptionsMultilineUnclosed(t *testing.T) {
+	l := lexer.Create("{{\n  width: 10,\n", lexer.WithMultiline())
+	token := lastToken(&l)
+
+	assert.Equal(t, lexer.TokenError, token.Type)
+	assert.True(t, errors.Is(token.Err, lexer.ErrUnclosedMeta))
+	assert.Equal(t, 3, token.Start.Line)
+}
+
+func TestOptionsMultilineRecovery(t *testing.T) {
+	l := lexer.Create("{{\n a: *,\n b: 1\n}} x {{c:?\n {{d}}", lexer.WithMultiline(), lexer.WithRecovery())
+
+	var values []string
+	for l.Scan() {
+		if l.Token().Type != lexer.TokenError {
+			values = append(values, l.Token().Value)
+		}
+	}
+
+	assert.Equal(t, []string{"{{", "a", "}}", " x ", "{{", "c", "{{", "d", "}}"}, values)
+	assert.True(t, errors.Is(l.Err(), lexer.ErrValueSyntax))
+}
+
+func TestOptionsEscape(t *testing.T) {
+	l := lexer.Create(`write \{{name}} for {{name}}, \\{{x}}`, lexer.WithEscape(`\`))
+
+	var tokens []lexer.Token
+	for l.Scan() {
+		tokens = append(tokens, l.Token())
+	}
+	assert.Nil(t, l.Err())
+	assert.Equal(t, 5, len(tokens))
+
+	assert.Equal(t, lexer.TokenPlainText, tokens[0].Type)
+	assert.Equal(t, `write \{{name}} for `, tokens[0].Value)
+	assert.Equal(t, `write {{name}} for `, l.Unescape(tokens[0]))
+
+	assert.Equal(t, lexer.TokenLeftMeta, tokens[1].Type)
+	assert.Equal(t, "name", tokens[2].Value)
+
+	assert.Equal(t, `, \\{{x}}`, tokens[4].Value)
+	assert.Equal(t, `, \{{x}}`, l.Unescape(tokens[4]))
+	assert.Equal(t, "name", l.Unescape(tokens[2]))
+}
+
+func TestOptionsEscapeDoubled(t *testing.T) {
+	l := lexer.Create("a {{{{b}} {{c}}", lexer.WithEscape("{{"))
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenPlainText, token.Type)
+	assert.Equal(t, "a {{{{b}} ", token.Value)
+	assert.Equal(t, "a {{b}} ", l.Unescape(token))
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenLeftMeta, token.Type)
+}
+
+func TestOptionsEscapeSplitRead(t *testing.T) {
+	input := strings.Repeat("x", 4095) + `\{{a}}`
+	l := lexer.CreateFromReader(iotest.OneByteReader(strings.NewReader(input)), lexer.WithEscape(`\`))
+
+	token := l.NextToken()
+	assert.Equal(t, input, token.Value)
+
+	token = l.NextToken()
+	assert.Equal(t, lexer.TokenEof, token.Type)
+}
+
+func TestOptionsComments(t *testing.T) {
+	input := "{{ width: 10 # pixels }} {{/* note, to: editors */}} {{a /* x */, b}}"
+
+	l := lexer.Create(input, lexer.WithLineComment("#"), lexer.WithBlockComment("/*", "*/"))
+	var values []string
+	for l.Scan() {
+		values = append(values, l.Token().Value)
+	}
+	assert.Nil(t, l.Err())
+	assert.Equal(t, []string{"{{", "width", "10", "}}", " ", "{{", "}}", " ", "{{", "a", "b", "}}"}, values)
+
+	l = lexer.Create(input, lexer.WithLineComment("#"), lexer.WithBlockComment("/*", "*/"), lexer.WithCommentTokens())
+	var comments []string
+	for l.Scan() {
+		if l.Token().Type == lexer.TokenComment {
+			comments = append(comments, l.Token().Value)
+		}
+	}
+	assert.Nil(t, l.Err())
+	assert.Equal(t, []string{"# pixels ", "/* note, to: editors */", "/* x */"}, comments)
+}
+
+func TestOptionsLineCommentMultiline(t *testing.T) {
+	l := lexer.Create("{{\n a: 1 // first\n b: 2 // second\n}}", lexer.WithMultiline(), lexer.WithLineComment("//"))
+
+	var values []string
+	for l.Scan() {
+		values = append(values, l.Token().Value)
+	}
+	assert.Nil(t, l.Err())
+	assert.Equal(t, []string{"{{", "a", "1", "b", "2", "}}"}, values)
+}
+
+func TestOptionsCommentErrors(t *testing.T) {
+	l := lexer.Create("{{ a /* open", lexer.WithBlockComment("/*", "*/"))
+	token := lastToken(&l)
+	assert.True(t, errors.Is(token.Err, lexer.ErrUnclosedComment))
+	assert.Equal(t, "unclosed comment", token.Value)
+
+	l = lexer.Create("{{ a # open", lexer.WithLineComment("#"))
+	token = lastToken(&l)
+	assert.True(t, errors.Is(token.Err, lexer.ErrUnclosedMeta))
+
+	l = lexer.Create("{{ a # x }}")
+	token = lastToken(&l)
+	assert.True(t, errors.Is(token.Err, lexer.ErrIdentifierSyntax))
+
+	l = lexer.Create("{{ a }}", lexer.WithBlockComment("/*", ""))
+	token = lastToken(&l)
+	assert.True(t, errors.Is(token.Err, lexer.ErrCommentZeroLength))
+}
+
+func TestOptionsKeywords(t *testing.T) {
+	l := lexer.Create("{{a: yes, b: off, c: true, d: nil, e: null}}",
+		lexer.WithBoolKeywords([]string{"yes", "on"}, []string{"no", "off"}),
+		lexer.WithNullKeywords("nil", "none"),
+	)
+
+	var types []lexer.TokenType
+	var truth []bool
+	for l.Scan() {
+		if token := l.Token(); token.Type != lexer.TokenMetaIdentifier {
+			types = append(types, token.Type)
+			if value, ok := l.Bool(token); ok {
+				truth = append(truth, value)
+			}
+		}
+	}
+
+	assert.Equal(t, []lexer.TokenType{
+		lexer.TokenLeftMeta,
+		lexer.TokenMetaBoolValue,
+		lexer.TokenMetaBoolValue,
+		lexer.TokenMetaTextValue,
+		lexer.TokenMetaNullValue,
+		lexer.TokenMetaTextValue,
+		lexer.TokenRightMeta,
+	}, types)
+	assert.Equal(t, []bool{true, false}, truth)
+}
+
+func TestOptionsLossless(t *testing.T) {
+	l := lexer.Create("a {{ x : 1 ,y,\tz:'q' }}b", lexer.WithLossless())
+
+	var types []lexer.TokenType
+	var values []string
+	for l.Scan() {
+		types = append(types, l.Token().Type)
+		values = append(values, l.Token().Value)
+	}
+	assert.Nil(t, l.Err())
+	assert.Equal(t, []string{"a ", "{{", " ", "x", " ", ":", " ", "1", " ", ",", "y", ",", "\t", "z", ":", "'q'", " ", "}}", "b"}, values)
+	assert.Equal(t, []lexer.TokenType{
+		lexer.TokenPlainText, lexer.TokenLeftMeta, lexer.TokenWhitespace,
+		lexer.TokenMetaIdentifier, lexer.TokenWhitespace, lexer.TokenValueIndicator, lexer.TokenWhitespace,
+		lexer.TokenMetaNumberValue, lexer.TokenWhitespace, lexer.TokenSeparator,
+		lexer.TokenMetaIdentifier, lexer.TokenSeparator, lexer.TokenWhitespace,
+		lexer.TokenMetaIdentifier, lexer.TokenValueIndicator, lexer.TokenMetaStringValue,
+		lexer.TokenWhitespace, lexer.TokenRightMeta, lexer.TokenPlainText,
+	}, types)
+}
+
+func TestOptionsLosslessRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain text only",
+		"{{a}}{{b:2}}",
+		"x {{  a:1,, b : 30s , c: \"q, r\"  }} y \\{{ z",
+		"{{\n\ta: 1 # note\n\tb: 2 /* two */\n}}",
+		"{{ a.b: -1.5e3 , c: null, d: true }}",
+	}
+	opts := []lexer.Option{
+		lexer.WithLossless(), lexer.WithMultiline(), lexer.WithEscape(`\`),
+		lexer.WithLineComment("#"), lexer.WithBlockComment("/*", "*/"),
+	}
+
+	for _, input := range inputs {
+		var text strings.Builder
+		for token, err := range lexer.All(context.Background(), input, opts...) {
+			assert.Nil(t, err, input)
+			text.WriteString(token.Value)
+		}
+		assert.Equal(t, input, text.String())
+
+		l := lexer.CreateFromReader(iotest.OneByteReader(strings.NewReader(input)), opts...)
+		text.Reset()
+		for l.Scan() {
+			text.WriteString(l.Token().Value)
+		}
+		assert.Nil(t, l.Err(), input)
+		assert.Equal(t, input, text.String())
+	}
+}
+
+func TestOptionsMeta(t *testing.T) {
+	l := lexer.Create("", lexer.WithDelimiters("<<", ">>"), lexer.WithSeparator('|'))
+	left, right, indicator, separator := l.Meta()
+	assert.Equal(t, "<<", left)
+	assert.Equal(t, ">>", right)
+	assert.Equal(t, ':', indicator)
+	assert.Equal(t, '|', separator)
+}
@@ -0,0 +1,69 @@
+package parse
+
+import "github.com/adroge/lexer"
+
+// Node is a part of a Document: a *TextNode or a *MetaNode.
+type Node interface {
+	Position() lexer.Position
+}
+
+// Document is the input as a sequence of text and meta blocks, in order.
+type Document struct {
+	Nodes []Node
+}
+
+// Meta returns the meta blocks of the document, in order.
+func (d *Document) Meta() (blocks []*MetaNode) {
+	for _, node := range d.Nodes {
+		if meta, ok := node.(*MetaNode); ok {
+			blocks = append(blocks, meta)
+		}
+	}
+	return
+}
+
+// TextNode is plain text outside meta blocks.
+type TextNode struct {
+	Text string         // the text, with escaped left metas unescaped
+	Pos  lexer.Position // where the text starts
+	End  lexer.Position // just after the text
+}
+
+func (n *TextNode) Position() lexer.Position {
+	return n.Pos
+}
+
+// MetaNode is a meta block such as {{width: 10, title}}.
+type MetaNode struct {
+	Entries []Entry
+	Pos     lexer.Position // where the left meta starts
+	End     lexer.Position // just after the right meta
+}
+
+func (n *MetaNode) Position() lexer.Position {
+	return n.Pos
+}
+
+// Lookup returns the first entry with key, and reports whether there is one.
+func (n *MetaNode) Lookup(key string) (entry Entry, ok bool) {
+	for _, entry = range n.Entries {
+		if entry.Key == key {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Entry is an identifier of a meta block with its optional value.
+// Value keeps the token so that its type and accessors, such as
+// Int64 or Duration, remain available.
+type Entry struct {
+	Key   string
+	Value lexer.Token    // TokenUndefined when the entry has no value
+	Pos   lexer.Position // where the key starts
+}
+
+// HasValue reports whether the entry was given a value.
+func (e Entry) HasValue() bool {
+	return e.Value.Type != lexer.TokenUndefined
+}
@@ -0,0 +1,46 @@
+package parse_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+	"github.com/adroge/lexer/parse"
+)
+
+func TestMetaNodeLookup(t *testing.T) {
+	meta := &parse.MetaNode{Entries: []parse.Entry{
+		{Key: "a", Value: lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "1"}},
+		{Key: "a", Value: lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "2"}},
+		{Key: "b"},
+	}}
+
+	entry, ok := meta.Lookup("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", entry.Value.Value)
+
+	entry, ok = meta.Lookup("b")
+	assert.True(t, ok)
+	assert.False(t, entry.HasValue())
+
+	_, ok = meta.Lookup("c")
+	assert.False(t, ok)
+}
+
+func TestDocumentMeta(t *testing.T) {
+	first, second := &parse.MetaNode{}, &parse.MetaNode{}
+	doc := &parse.Document{Nodes: []parse.Node{
+		&parse.TextNode{Text: "x"},
+		first,
+		&parse.TextNode{Text: "y"},
+		second,
+	}}
+	assert.Equal(t, []*parse.MetaNode{first, second}, doc.Meta())
+}
+
+func TestNodePosition(t *testing.T) {
+	position := lexer.Position{Offset: 3, Line: 1, Column: 4}
+	assert.Equal(t, position, (&parse.TextNode{Pos: position}).Position())
+	assert.Equal(t, position, (&parse.MetaNode{Pos: position}).Position())
+}
@@ -0,0 +1,139 @@
+// Package parse builds a Document from the tokens of a lexer, collecting
+// the identifiers and values of each meta block into a MetaNode.
+//
+//	doc, err := parse.Parse("Hello {{name: world, width: 10}}!")
+//	if err != nil {
+//		return err
+//	}
+//	for _, meta := range doc.Meta() {
+//		for _, entry := range meta.Entries {
+//			fmt.Println(entry.Key, entry.Value.Value)
+//		}
+//	}
+package parse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/adroge/lexer"
+)
+
+// Error is a token that cannot appear where it was found. Syntax errors
+// found by the lexer are reported as *lexer.LexError instead.
+type Error struct {
+	Pos     lexer.Position
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+// Parse lexes input with opts and returns its document.
+//
+// On error, the document holds what was parsed before the error. With
+// lexer.WithRecovery, it holds every block that lexed without an error,
+// and the errors are joined.
+func Parse(input string, opts ...lexer.Option) (*Document, error) {
+	l := lexer.Create(input, opts...)
+	return parse(l.All(context.Background()), l.Unescape)
+}
+
+// ParseReader is like Parse, reading the input from reader.
+func ParseReader(reader io.Reader, opts ...lexer.Option) (*Document, error) {
+	l := lexer.CreateFromReader(reader, opts...)
+	return parse(l.All(context.Background()), l.Unescape)
+}
+
+// ParseTokens returns the document of tokens, as yielded by the All
+// iterator of a lexer. The text of TextNodes is kept as it was lexed,
+// escapes included.
+func ParseTokens(tokens iter.Seq2[lexer.Token, error]) (*Document, error) {
+	return parse(tokens, func(token lexer.Token) string { return token.Value })
+}
+
+func parse(tokens iter.Seq2[lexer.Token, error], unescape func(lexer.Token) string) (*Document, error) {
+	doc := &Document{}
+	var (
+		meta      *MetaNode // block being parsed, nil outside meta blocks
+		recovered bool      // a block was dropped after a lexer error
+		errs      []error
+	)
+
+	for token, err := range tokens {
+		if err != nil {
+			errs = append(errs, err)
+			meta, recovered = nil, true
+			continue
+		}
+
+		switch token.Type {
+		case lexer.TokenComment, lexer.TokenWhitespace, lexer.TokenSeparator, lexer.TokenValueIndicator:
+			continue
+		case lexer.TokenRightMeta:
+			if meta == nil && recovered {
+				recovered = false
+				continue
+			}
+		}
+		recovered = false
+
+		switch token.Type {
+		case lexer.TokenPlainText:
+			if meta != nil {
+				return doc, unexpected(token, errs)
+			}
+			doc.Nodes = append(doc.Nodes, &TextNode{Text: unescape(token), Pos: token.Start, End: token.End})
+		case lexer.TokenLeftMeta:
+			if meta != nil {
+				return doc, unexpected(token, errs)
+			}
+			meta = &MetaNode{Pos: token.Start}
+		case lexer.TokenMetaIdentifier:
+			if meta == nil {
+				return doc, unexpected(token, errs)
+			}
+			meta.Entries = append(meta.Entries, Entry{Key: token.Value, Pos: token.Start})
+		case lexer.TokenMetaNumberValue, lexer.TokenMetaTextValue, lexer.TokenMetaStringValue,
+			lexer.TokenMetaBoolValue, lexer.TokenMetaNullValue, lexer.TokenMetaQuantityValue:
+			if meta == nil || len(meta.Entries) == 0 || meta.Entries[len(meta.Entries)-1].HasValue() {
+				return doc, unexpected(token, errs)
+			}
+			meta.Entries[len(meta.Entries)-1].Value = token
+		case lexer.TokenRightMeta:
+			if meta == nil {
+				return doc, unexpected(token, errs)
+			}
+			meta.End = token.End
+			doc.Nodes = append(doc.Nodes, meta)
+			meta = nil
+		case lexer.TokenEof:
+		default:
+			return doc, unexpected(token, errs)
+		}
+	}
+
+	if meta != nil {
+		errs = append(errs, &Error{Pos: meta.Pos, Message: "unclosed meta block"})
+	}
+	return doc, join(errs)
+}
+
+// unexpected returns the error for token, joined with the errors before it
+func unexpected(token lexer.Token, errs []error) error {
+	err := &Error{Pos: token.Start, Message: fmt.Sprintf("unexpected %s %q", token.Type, token.Value)}
+	return join(append(errs, err))
+}
+
+// join returns a single error as it is, so that it can be type asserted,
+// and joins several
+func join(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package parse_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+	"github.com/adroge/lexer/parse"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse.Parse("start {{setting, x:y}} middle {{pi:3.14}}")
+	assert.Nil(t, err)
+	assert.Len(t, doc.Nodes, 4)
+
+	text := doc.Nodes[0].(*parse.TextNode)
+	assert.Equal(t, "start ", text.Text)
+	assert.Equal(t, lexer.Position{Offset: 0, Line: 1, Column: 1}, text.Pos)
+	assert.Equal(t, lexer.Position{Offset: 6, Line: 1, Column: 7}, text.End)
+
+	meta := doc.Nodes[1].(*parse.MetaNode)
+	assert.Equal(t, lexer.Position{Offset: 6, Line: 1, Column: 7}, meta.Pos)
+	assert.Equal(t, lexer.Position{Offset: 22, Line: 1, Column: 23}, meta.End)
+	assert.Len(t, meta.Entries, 2)
+	assert.Equal(t, "setting", meta.Entries[0].Key)
+	assert.False(t, meta.Entries[0].HasValue())
+	assert.Equal(t, lexer.Position{Offset: 8, Line: 1, Column: 9}, meta.Entries[0].Pos)
+	assert.Equal(t, "x", meta.Entries[1].Key)
+	assert.Equal(t, lexer.TokenMetaTextValue, meta.Entries[1].Value.Type)
+	assert.Equal(t, "y", meta.Entries[1].Value.Value)
+
+	assert.Equal(t, " middle ", doc.Nodes[2].(*parse.TextNode).Text)
+
+	pi := doc.Nodes[3].(*parse.MetaNode)
+	assert.Equal(t, "pi", pi.Entries[0].Key)
+	value, err := pi.Entries[0].Value.Float64()
+	assert.Nil(t, err)
+	assert.Equal(t, 3.14, value)
+}
+
+func TestParseValueTypes(t *testing.T) {
+	doc, err := parse.Parse(`{{n: 1, q: 30s, s: "a b", b: true, z: null, t: text}}`)
+	assert.Nil(t, err)
+
+	types := map[string]lexer.TokenType{
+		"n": lexer.TokenMetaNumberValue,
+		"q": lexer.TokenMetaQuantityValue,
+		"s": lexer.TokenMetaStringValue,
+		"b": lexer.TokenMetaBoolValue,
+		"z": lexer.TokenMetaNullValue,
+		"t": lexer.TokenMetaTextValue,
+	}
+	meta := doc.Meta()[0]
+	for key, tokenType := range types {
+		entry, ok := meta.Lookup(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, tokenType, entry.Value.Type, key)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	doc, err := parse.Parse("a <<x=1|y>> \\<<b", lexer.WithDelimiters("<<", ">>"),
+		lexer.WithValueIndicator('='), lexer.WithSeparator('|'), lexer.WithEscape("\\"))
+	assert.Nil(t, err)
+	assert.Len(t, doc.Nodes, 3)
+	assert.Equal(t, []parse.Entry{
+		{Key: "x", Value: doc.Nodes[1].(*parse.MetaNode).Entries[0].Value, Pos: lexer.Position{Offset: 4, Line: 1, Column: 5}},
+		{Key: "y", Pos: lexer.Position{Offset: 8, Line: 1, Column: 9}},
+	}, doc.Nodes[1].(*parse.MetaNode).Entries)
+	assert.Equal(t, " <<b", doc.Nodes[2].(*parse.TextNode).Text)
+}
+
+func TestParseComments(t *testing.T) {
+	doc, err := parse.Parse("{{a: 1 # note\n}}", lexer.WithMultiline(), lexer.WithLineComment("#"), lexer.WithCommentTokens())
+	assert.Nil(t, err)
+	meta := doc.Meta()[0]
+	assert.Len(t, meta.Entries, 1)
+	assert.Equal(t, "1", meta.Entries[0].Value.Value)
+}
+
+func TestParseError(t *testing.T) {
+	doc, err := parse.Parse("text {{a: 1}} more {{b:*}} end")
+	var lexErr *lexer.LexError
+	assert.True(t, errors.As(err, &lexErr))
+	assert.True(t, errors.Is(err, lexer.ErrValueSyntax))
+	assert.Equal(t, lexer.Position{Offset: 23, Line: 1, Column: 24}, lexErr.Start)
+	assert.Len(t, doc.Nodes, 3)
+}
+
+func TestParseRecovery(t *testing.T) {
+	doc, err := parse.Parse("{{a:*}} x {{b: 1}} y {{c d:*}} z", lexer.WithRecovery())
+	assert.NotNil(t, err)
+	assert.True(t, errors.Is(err, lexer.ErrValueSyntax))
+
+	var texts []string
+	for _, node := range doc.Nodes {
+		if text, ok := node.(*parse.TextNode); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	assert.Equal(t, []string{" x ", " y ", " z"}, texts)
+	assert.Len(t, doc.Meta(), 1)
+	assert.Equal(t, "b", doc.Meta()[0].Entries[0].Key)
+}
+
+func TestParseReader(t *testing.T) {
+	doc, err := parse.ParseReader(strings.NewReader("x {{y: 2}}"))
+	assert.Nil(t, err)
+	assert.Len(t, doc.Nodes, 2)
+	assert.Equal(t, "2", doc.Meta()[0].Entries[0].Value.Value)
+}
+
+func TestParseTokens(t *testing.T) {
+	doc, err := parse.ParseTokens(lexer.All(context.Background(), "x {{y}}"))
+	assert.Nil(t, err)
+	assert.Len(t, doc.Nodes, 2)
+}
+
+func TestParseTokensUnexpected(t *testing.T) {
+	tokens := func(yield func(lexer.Token, error) bool) {
+		_ = yield(lexer.Token{Type: lexer.TokenLeftMeta, Value: "{{"}, nil) &&
+			yield(lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "1", Start: lexer.Position{Offset: 2, Line: 1, Column: 3}}, nil)
+	}
+	_, err := parse.ParseTokens(tokens)
+	var parseErr *parse.Error
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 3, parseErr.Pos.Column)
+	assert.Equal(t, `1:3: unexpected MetaNumberValue "1"`, err.Error())
+}
+
+func TestParseTokensUnclosed(t *testing.T) {
+	tokens := func(yield func(lexer.Token, error) bool) {
+		_ = yield(lexer.Token{Type: lexer.TokenLeftMeta, Value: "{{", Start: lexer.Position{Line: 1, Column: 1}}, nil) &&
+			yield(lexer.Token{Type: lexer.TokenMetaIdentifier, Value: "a"}, nil)
+	}
+	doc, err := parse.ParseTokens(tokens)
+	assert.Equal(t, "1:1: unclosed meta block", err.Error())
+	assert.Empty(t, doc.Nodes)
+}
+
+func TestParseLossless(t *testing.T) {
+	doc, err := parse.Parse("{{ a : 1 , b }}", lexer.WithLossless())
+	assert.Nil(t, err)
+	meta := doc.Meta()[0]
+	assert.Len(t, meta.Entries, 2)
+	assert.Equal(t, "1", meta.Entries[0].Value.Value)
+	assert.Equal(t, "b", meta.Entries[1].Key)
+}
@@ -0,0 +1,143 @@
+package lexer_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+)
+
+func collect(l interface{ NextToken() lexer.Token }) (tokens []lexer.Token) {
+	for token := l.NextToken(); token.Type != lexer.TokenUndefined; token = l.NextToken() {
+		tokens = append(tokens, token)
+	}
+	return
+}
+
+func TestReaderMatchesString(t *testing.T) {
+	input := "start {{setting, x:y}} middle {{pi:3.14}} end text ünïcode.\n{{ a : b, n: 1_000, h: 0x1p-2, f: .5, q: 10MB }}"
+
+	expected := lexer.Create(input)
+	expected.Run(context.Background())
+
+	actual := lexer.CreateFromReader(iotest.OneByteReader(strings.NewReader(input)))
+	actual.Run(context.Background())
+
+	assert.Equal(t, collect(&expected), collect(&actual))
+}
+
+func TestReaderLongNumber(t *testing.T) {
+	input := strings.Repeat("x", 4085) + "{{a: 1_000}}"
+
+	l := lexer.CreateFromReader(strings.NewReader(input))
+	l.Run(context.Background())
+
+	tokens := collect(&l)
+	assert.Equal(t, 6, len(tokens))
+	assert.Equal(t, lexer.TokenMetaNumberValue, tokens[3].Type)
+	assert.Equal(t, "1_000", tokens[3].Value)
+}
+
+func TestReaderLongQuantity(t *testing.T) {
+	input := strings.Repeat("x", 4085) + "{{b: 10MB}}"
+
+	for name, reader := range map[string]io.Reader{
+		"whole":    strings.NewReader(input),
+		"one byte": iotest.OneByteReader(strings.NewReader(input)),
+	} {
+		l := lexer.CreateFromReader(reader)
+		l.Run(context.Background())
+
+		tokens := collect(&l)
+		if assert.Equal(t, 6, len(tokens), name) {
+			assert.Equal(t, lexer.TokenMetaQuantityValue, tokens[3].Type, name)
+			assert.Equal(t, "10MB", tokens[3].Value, name)
+		}
+	}
+}
+
+func TestReaderSplitDelimiters(t *testing.T) {
+	input := strings.Repeat("x", 4095) + "<<<a=1>>>" + strings.Repeat("y", 5000)
+
+	l := lexer.CreateFromReader(iotest.HalfReader(strings.NewReader(input)), lexer.WithDelimiters("<<<", ">>>"), lexer.WithValueIndicator('='))
+	l.Run(context.Background())
+
+	tokens := collect(&l)
+	assert.Equal(t, 7, len(tokens))
+	assert.Equal(t, strings.Repeat("x", 4095), tokens[0].Value)
+	assert.Equal(t, lexer.TokenLeftMeta, tokens[1].Type)
+	assert.Equal(t, 4095, tokens[1].Start.Offset)
+	assert.Equal(t, "a", tokens[2].Value)
+	assert.Equal(t, "1", tokens[3].Value)
+	assert.Equal(t, lexer.TokenRightMeta, tokens[4].Type)
+	assert.Equal(t, strings.Repeat("y", 5000), tokens[5].Value)
+	assert.Equal(t, 4104, tokens[5].Start.Offset)
+	assert.Equal(t, lexer.TokenEof, tokens[6].Type)
+}
+
+func TestReaderError(t *testing.T) {
+	reader := iotest.DataErrReader(&failingReader{data: "text {{a", err: errors.New("connection reset")})
+
+	l := lexer.CreateFromReader(reader)
+	l.Run(context.Background())
+
+	tokens := collect(&l)
+	assert.Equal(t, 4, len(tokens))
+	assert.Equal(t, lexer.TokenError, tokens[3].Type)
+	assert.Equal(t, "read error: connection reset", tokens[3].Value)
+}
+
+func TestReaderErrorInText(t *testing.T) {
+	l := lexer.CreateFromReader(&failingReader{data: "text", err: errors.New("timeout")})
+	l.Run(context.Background())
+
+	tokens := collect(&l)
+	assert.Equal(t, 2, len(tokens))
+	assert.Equal(t, "text", tokens[0].Value)
+	assert.Equal(t, lexer.TokenError, tokens[1].Type)
+	assert.Equal(t, "read error: timeout", tokens[1].Value)
+}
+
+// failingReader returns data and then err
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestReaderLongText(t *testing.T) {
+	input := strings.Repeat("log line ü \\{{ not meta\n", 20000) + "{{a: 1}}"
+
+	l := lexer.CreateFromReader(iotest.HalfReader(strings.NewReader(input)), lexer.WithEscape(`\`))
+	var text strings.Builder
+	chunks := 0
+	for l.Scan() {
+		token := l.Token()
+		if token.Type != lexer.TokenPlainText {
+			continue
+		}
+		chunks++
+		assert.LessOrEqual(t, len(token.Value), 16*1024+8)
+		assert.False(t, strings.HasSuffix(token.Value, `\`), "escape split from its left meta")
+		assert.Equal(t, input[token.Start.Offset:token.End.Offset], token.Value)
+		text.WriteString(token.Value)
+	}
+	assert.Nil(t, l.Err())
+	assert.Greater(t, chunks, 1)
+	assert.Equal(t, input[:len(input)-len("{{a: 1}}")], text.String())
+	assert.Equal(t, lexer.TokenEof, l.Token().Type)
+}
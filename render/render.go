@@ -0,0 +1,121 @@
+// Package render replaces the meta blocks of a document with text given
+// by a Resolver, copying the plain text around them.
+//
+//	err := render.Render(os.Stdout, "Hello {{name}}!", render.Map{"name": "world"})
+//	// Hello world!
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/adroge/lexer"
+	"github.com/adroge/lexer/parse"
+)
+
+// Resolver gives the text that replaces an entry of a meta block.
+// value is the value given to key, TokenUndefined when there is none.
+// ok is false when key is not known to the resolver.
+type Resolver interface {
+	Resolve(key string, value lexer.Token) (text string, ok bool)
+}
+
+// ResolverFunc is a function used as a Resolver.
+type ResolverFunc func(key string, value lexer.Token) (text string, ok bool)
+
+func (f ResolverFunc) Resolve(key string, value lexer.Token) (string, bool) {
+	return f(key, value)
+}
+
+// Map is a Resolver replacing each key with its text, whatever its value.
+type Map map[string]string
+
+func (m Map) Resolve(key string, _ lexer.Token) (text string, ok bool) {
+	text, ok = m[key]
+	return
+}
+
+// Error is an entry that the resolver could not resolve.
+type Error struct {
+	Key string
+	Pos lexer.Position // where the key starts
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: unresolved key %q", e.Pos, e.Key)
+}
+
+type config struct {
+	lenient bool
+	lexOpts []lexer.Option
+}
+
+// Option changes how Render works.
+type Option func(*config)
+
+// WithLenient leaves a meta block as it is written in the input when
+// one of its entries is unresolved, instead of failing.
+func WithLenient() Option {
+	return func(c *config) {
+		c.lenient = true
+	}
+}
+
+// WithLexerOptions sets the options of the lexer, such as its delimiters.
+func WithLexerOptions(opts ...lexer.Option) Option {
+	return func(c *config) {
+		c.lexOpts = append(c.lexOpts, opts...)
+	}
+}
+
+// Render writes input to w with each meta block replaced by the text that
+// resolver gives for its entries, in order. Plain text is copied, with
+// escaped left metas unescaped. Unless WithLenient is given, an unresolved
+// entry fails with an *Error.
+//
+// Nothing is written when input has a syntax error or an unresolved entry.
+func Render(w io.Writer, input string, resolver Resolver, opts ...Option) error {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	doc, err := parse.Parse(input, c.lexOpts...)
+	if err != nil {
+		return err
+	}
+
+	var output strings.Builder
+	for _, node := range doc.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			output.WriteString(node.Text)
+		case *parse.MetaNode:
+			text, err := resolve(node, resolver)
+			if err != nil && !c.lenient {
+				return err
+			}
+			if err != nil {
+				text = input[node.Pos.Offset:node.End.Offset]
+			}
+			output.WriteString(text)
+		}
+	}
+
+	_, err = io.WriteString(w, output.String())
+	return err
+}
+
+// resolve returns the text of the entries of meta
+func resolve(meta *parse.MetaNode, resolver Resolver) (string, error) {
+	var text strings.Builder
+	for _, entry := range meta.Entries {
+		resolved, ok := resolver.Resolve(entry.Key, entry.Value)
+		if !ok {
+			return "", &Error{Key: entry.Key, Pos: entry.Pos}
+		}
+		text.WriteString(resolved)
+	}
+	return text.String(), nil
+}
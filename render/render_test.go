@@ -0,0 +1,74 @@
+package render_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+	"github.com/adroge/lexer/render"
+)
+
+func TestRender(t *testing.T) {
+	var output strings.Builder
+	err := render.Render(&output, "Hello {{name}}, {{greeting}}!", render.Map{"name": "Ann", "greeting": "welcome"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello Ann, welcome!", output.String())
+}
+
+func TestRenderValues(t *testing.T) {
+	resolver := render.ResolverFunc(func(key string, value lexer.Token) (string, bool) {
+		text, err := value.Unquote()
+		switch {
+		case err != nil:
+			return "", false
+		case key == "upper":
+			return strings.ToUpper(text), true
+		case key == "repeat":
+			return text + text, true
+		}
+		return "", false
+	})
+
+	var output strings.Builder
+	err := render.Render(&output, `a {{upper: "bc"}} d {{repeat: x, upper: y}}`, resolver)
+	assert.Nil(t, err)
+	assert.Equal(t, "a BC d xxY", output.String())
+}
+
+func TestRenderStrict(t *testing.T) {
+	var output strings.Builder
+	err := render.Render(&output, "Hello {{name}}\n{{ missing }}", render.Map{"name": "Ann"})
+
+	var renderErr *render.Error
+	assert.True(t, errors.As(err, &renderErr))
+	assert.Equal(t, "missing", renderErr.Key)
+	assert.Equal(t, lexer.Position{Offset: 18, Line: 2, Column: 4}, renderErr.Pos)
+	assert.Equal(t, `2:4: unresolved key "missing"`, err.Error())
+	assert.Empty(t, output.String())
+}
+
+func TestRenderLenient(t *testing.T) {
+	var output strings.Builder
+	err := render.Render(&output, "Hello {{name}}, {{ missing: 1,name }} \\{{x}}", render.Map{"name": "Ann"},
+		render.WithLenient(), render.WithLexerOptions(lexer.WithEscape("\\")))
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello Ann, {{ missing: 1,name }} {{x}}", output.String())
+}
+
+func TestRenderDelimiters(t *testing.T) {
+	var output strings.Builder
+	err := render.Render(&output, "<<a>> {{b}}", render.Map{"a": "1"},
+		render.WithLexerOptions(lexer.WithDelimiters("<<", ">>")))
+	assert.Nil(t, err)
+	assert.Equal(t, "1 {{b}}", output.String())
+}
+
+func TestRenderSyntaxError(t *testing.T) {
+	var output strings.Builder
+	err := render.Render(&output, "Hello {{name", render.Map{"name": "Ann"}, render.WithLenient())
+	assert.True(t, errors.Is(err, lexer.ErrUnclosedMeta))
+	assert.Empty(t, output.String())
+}
@@ -2,37 +2,35 @@ package lexer
 
 import (
 	"errors"
-	"strings"
+	"fmt"
+	"slices"
+	"unicode"
 )
 
 type stateFn func(*lexer) stateFn
 
 var (
-	_LEFT_META                  string = "{{"
-	_RIGHT_META                 string = "}}"
-	_IDENTIFIER_VALUE_INDICATOR rune   = ':'
-	_IDENTIFIER_SEPARATOR       rune   = ','
-
 	ErrMetaZeroLength     = errors.New("meta tag cannot be zero length")
 	ErrMetaIndicatorMatch = errors.New("indicator cannot match separator")
+	ErrCommentZeroLength  = errors.New("block comment markers cannot be zero length")
 )
 
 // SetMeta globally sets meta values to something other than the default.
+// Lexers created afterwards use these values unless overridden by an Option;
+// lexers that already exist are not affected.
 //
 //		err := lexer.SetMeta("<<", ">>", '=', '|')
 func SetMeta(left, right string, valueIndicator, valueSeparator rune) (err error) {
-	if len(left) == 0 || len(right) == 0 {
-		return ErrMetaZeroLength
-	}
+	defaultConfigLock.Lock()
+	defer defaultConfigLock.Unlock()
 
-	if valueIndicator == valueSeparator {
-		return ErrMetaIndicatorMatch
+	c := defaultConfig
+	c.leftMeta, c.rightMeta = left, right
+	c.valueIndicator, c.separator = valueIndicator, valueSeparator
+	if err = c.validate(); err != nil {
+		return
 	}
-
-	_LEFT_META = left
-	_RIGHT_META = right
-	_IDENTIFIER_VALUE_INDICATOR = valueIndicator
-	_IDENTIFIER_SEPARATOR = valueSeparator
+	defaultConfig = c
 
 	return
 }
@@ -51,23 +49,67 @@ func isHex(r rune) bool {
 		r >= 'A' && r <= 'F'
 }
 
+func isUnit(r rune) bool {
+	return r == '%' || unicode.IsLetter(r)
+}
+
+func isQuote(r rune) bool {
+	return r == '"' || r == '\'' || r == '`'
+}
+
 func isLetter(r rune) bool {
 	return r >= 'a' && r <= 'z' ||
 		r >= 'A' && r <= 'Z'
 }
 
-func isIdentifierSeparator(r rune) bool {
-	return r == _IDENTIFIER_SEPARATOR
+// isMetaSpace reports whether r is white space inside the meta tags,
+// which includes line endings when meta blocks may span lines
+func (l *lexer) isMetaSpace(r rune) bool {
+	return isSpace(r) || l.config.multiline && (r == _NEWLINE || r == '\r')
+}
+
+// isIdentifierStart reports whether r can begin an identifier or text value:
+// a letter or an underscore
+func (l *lexer) isIdentifierStart(r rune) bool {
+	if l.config.unicode {
+		return r == '_' || unicode.IsLetter(r)
+	}
+	return r == '_' || isLetter(r)
+}
+
+// isIdentifierPart reports whether r can continue an identifier or text value:
+// a letter, digit, underscore or hyphen
+func (l *lexer) isIdentifierPart(r rune) bool {
+	if r == '_' || r == '-' {
+		return true
+	}
+	if l.config.unicode {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc)
+	}
+	return isLetter(r) || isNumber(r)
 }
 
-func isIdentifierValueIndicator(r rune) bool {
-	return r == _IDENTIFIER_VALUE_INDICATOR
+func (l *lexer) isIdentifierSeparator(r rune) bool {
+	return r == l.config.separator
 }
 
-// lexText is the entry point and identifies text outside meta tags
+func (l *lexer) isIdentifierValueIndicator(r rune) bool {
+	return r == l.config.valueIndicator
+}
+
+// lexText is the entry point and identifies text outside meta tags.
+// An escaped left meta is kept in the text, escape included. Text read
+// from a reader is emitted in chunks, between runes and escapes.
 func lexText(l *lexer) stateFn {
 	for {
-		if strings.HasPrefix(l.input[l.pos:], _LEFT_META) {
+		if l.streaming && l.pos-l.start >= _TEXT_CHUNK {
+			l.emit(TokenPlainText)
+		}
+		if l.escapedLeftMeta != "" && l.hasPrefix(l.escapedLeftMeta) {
+			l.skip(len(l.escapedLeftMeta))
+			continue
+		}
+		if l.hasPrefix(l.config.leftMeta) {
 			if l.pos > l.start {
 				l.emit(TokenPlainText)
 			}
@@ -81,18 +123,21 @@ func lexText(l *lexer) stateFn {
 	if l.pos > l.start {
 		l.emit(TokenPlainText)
 	}
+	if l.readErr != nil {
+		return l.errorf(ErrRead, nil, "read error: %s", l.readErr)
+	}
 	l.emit(TokenEof)
 	return nil // stop run loop
 }
 
 func lexLeftMeta(l *lexer) stateFn {
-	l.pos += len(_LEFT_META)
+	l.skip(len(l.config.leftMeta))
 	l.emit(TokenLeftMeta)
 	return lexInsideMeta // Now inside {{ }}
 }
 
 func lexRightMeta(l *lexer) stateFn {
-	l.pos += len(_RIGHT_META)
+	l.skip(len(l.config.rightMeta))
 	l.emit(TokenRightMeta)
 	return lexText // now outside {{ }}
 }
@@ -100,41 +145,58 @@ func lexRightMeta(l *lexer) stateFn {
 // lexInsideMeta is inside the defined meta tags
 func lexInsideMeta(l *lexer) stateFn {
 	for {
-		if strings.HasPrefix(l.input[l.pos:], _RIGHT_META) {
+		if l.hasPrefix(l.config.rightMeta) {
 			return lexRightMeta
 		}
+		if l.config.lineComment != "" && l.hasPrefix(l.config.lineComment) {
+			return lexLineComment
+		}
+		if l.config.blockCommentOpen != "" && l.hasPrefix(l.config.blockCommentOpen) {
+			return lexBlockComment
+		}
 		switch r := l.next(); {
-		case r == _EOF || r == _NEWLINE:
-			return l.errorf("unclosed meta")
-		case isSpace(r):
-			l.ignore()
-		case isIdentifierSeparator(r):
-			l.ignore()
-		case isLetter(r):
+		case r == _EOF || r == _NEWLINE && !l.config.multiline:
+			l.backup()
+			return l.errorf(ErrUnclosedMeta, expectRightMeta, "unclosed meta")
+		case l.isMetaSpace(r):
+			l.acceptRun(_MetaSpace)
+			l.emitTrivia(TokenWhitespace)
+		case l.isIdentifierSeparator(r):
+			l.emitTrivia(TokenSeparator)
+		case l.isIdentifierStart(r):
 			l.backup()
 			return lexMetaIdentifier
 		default:
-			return l.errorf("identifier syntax: %q", l.input[l.start:l.pos])
+			return l.errorf(ErrIdentifierSyntax, expectIdentifier, "identifier syntax: %q", l.slice(l.start, l.pos))
 		}
 	}
 }
 
-// lexMetaIdentifier identifies an identifier inside the metadata
+// lexMetaIdentifier identifies an identifier inside the metadata,
+// which may be a path of identifiers joined by dots
 func lexMetaIdentifier(l *lexer) stateFn {
-	l.acceptRun(_Letters)
+	l.acceptRun(_Identifier)
+	for l.accept(".") {
+		if !l.isIdentifierStart(l.next()) {
+			return l.errorf(ErrIdentifierSyntax, expectIdentifier, "identifier syntax: %q", l.slice(l.start, l.pos))
+		}
+		l.acceptRun(_Identifier)
+	}
 	l.emit(TokenMetaIdentifier)
 
 	for {
 		switch r := l.next(); {
-		case r == _EOF || r == _NEWLINE:
-			return l.errorf("unclosed meta")
-		case isSpace(r):
-			l.ignore()
-		case isIdentifierSeparator(r):
-			l.ignore()
+		case r == _EOF || r == _NEWLINE && !l.config.multiline:
+			l.backup()
+			return l.errorf(ErrUnclosedMeta, expectRightMeta, "unclosed meta")
+		case l.isMetaSpace(r):
+			l.acceptRun(_MetaSpace)
+			l.emitTrivia(TokenWhitespace)
+		case l.isIdentifierSeparator(r):
+			l.emitTrivia(TokenSeparator)
 			return lexInsideMeta
-		case isIdentifierValueIndicator(r):
-			l.ignore()
+		case l.isIdentifierValueIndicator(r):
+			l.emitTrivia(TokenValueIndicator)
 			return lexIdentifierValue
 		default:
 			l.backup()
@@ -146,45 +208,284 @@ func lexMetaIdentifier(l *lexer) stateFn {
 // lexIdentifierValue identifies an identifier value after an identifier
 func lexIdentifierValue(l *lexer) stateFn {
 	for {
+		value := l.position()
 		switch r := l.next(); {
-		case r == _EOF || r == _NEWLINE:
-			return l.errorf("unclosed meta")
-		case isSpace(r):
-			l.ignore()
+		case r == _EOF || r == _NEWLINE && !l.config.multiline:
+			l.backup()
+			return l.errorf(ErrUnclosedMeta, expectValue, "unclosed meta")
+		case l.isMetaSpace(r):
+			l.acceptRun(_MetaSpace)
+			l.emitTrivia(TokenWhitespace)
 		case r == '+' || r == '-' || '0' <= r && r <= '9':
 			l.backup()
 			return lexMetaNumberValue
-		case isLetter(r):
+		case r == '.' && isNumber(l.peek()):
+			l.reset(value) // a number such as .5
+			return lexMetaNumberValue
+		case l.isIdentifierStart(r):
 			l.backup()
 			return lexMetaTextValue
+		case isQuote(r):
+			l.backup()
+			return lexMetaStringValue
 		default:
-			return l.errorf("value syntax: %q", l.input[l.start:l.pos])
+			return l.errorf(ErrValueSyntax, expectValue, "value syntax: %q", l.slice(l.start, l.pos))
 		}
 	}
 }
 
-// lexNumber identifies a number inside the metadata
+// lexMetaNumberValue identifies a number inside the metadata. A number
+// follows the syntax of Go's integer and floating-point literals, such as
+// 42, 0x2a, 0o52, 0b101010, 1_000, 4.2, .5 or 4.2e1, with an optional sign.
 func lexMetaNumberValue(l *lexer) stateFn {
 	l.accept("+-")
-	digits := _Numbers
-	if l.accept("0") && l.accept("xX") {
-		digits = _Hex
+	literal := l.offset + l.pos // absolute, as reading may slide the input
+
+	base, prefix := 10, rune(0)
+	sawDigit, sawSeparator := false, false
+	var invalid rune // first digit too large for an octal or binary literal
+	if l.peek() != '.' {
+		if l.accept("0") {
+			switch unicode.ToLower(l.peek()) {
+			case 'x':
+				l.next()
+				base, prefix = 16, 'x'
+			case 'o':
+				l.next()
+				base, prefix = 8, 'o'
+			case 'b':
+				l.next()
+				base, prefix = 2, 'b'
+			default:
+				base, prefix = 8, '0'
+				sawDigit = true // the leading 0
+			}
+		}
+		digit, separator := l.acceptDigits(base, &invalid)
+		sawDigit, sawSeparator = sawDigit || digit, sawSeparator || separator
 	}
-	l.acceptRun(digits)
+
+	float := false
 	if l.accept(".") {
-		l.acceptRun(digits)
+		float = true
+		if prefix == 'o' || prefix == 'b' {
+			return l.numberErrorf("invalid radix point in %s", literalName(prefix))
+		}
+		digit, separator := l.acceptDigits(base, &invalid)
+		sawDigit, sawSeparator = sawDigit || digit, sawSeparator || separator
+	}
+	if !sawDigit {
+		return l.numberErrorf("%s has no digits", literalName(prefix))
+	}
+
+	// a decimal number may be followed by a unit
+	unitAllowed := (prefix == 0 || prefix == '0') && len(l.config.units) > 0
+
+	switch exponent := unicode.ToLower(l.peek()); {
+	case exponent == 'p' && prefix != 'x' && unitAllowed:
+		// not an exponent, but maybe a unit such as px
+	case exponent == 'e' && prefix != 0 && prefix != '0':
+		l.next()
+		return l.numberErrorf("%q exponent requires decimal mantissa", exponent)
+	case exponent == 'p' && prefix != 'x':
+		l.next()
+		return l.numberErrorf("%q exponent requires hexadecimal mantissa", exponent)
+	case exponent == 'e' || exponent == 'p':
+		mantissa := l.position()
+		l.next()
+		l.accept("+-")
+		digit, separator := l.acceptDigits(10, nil)
+		switch {
+		case digit:
+			float = true
+			sawSeparator = sawSeparator || separator
+		case unitAllowed:
+			l.reset(mantissa) // not an exponent, but maybe a unit such as EB
+		default:
+			return l.numberErrorf("exponent has no digits")
+		}
+	case prefix == 'x' && float:
+		return l.numberErrorf("hexadecimal mantissa requires a 'p' exponent")
+	}
+	if l.peek() == '.' {
+		for l.accept(".") {
+			l.acceptDigits(base, nil)
+		}
+		return l.numberErrorf("unexpected radix point")
+	}
+
+	if !float && invalid != 0 {
+		return l.numberErrorf("invalid digit %q in %s", invalid, literalName(prefix))
+	}
+	if sawSeparator && !validSeparators(l.slice(literal-l.offset, l.pos)) {
+		return l.numberErrorf("'_' must separate successive digits")
+	}
+
+	// the next rune must not be a letter, unless it starts a unit
+	if unitAllowed && isUnit(l.peek()) {
+		unit := l.offset + l.pos
+		for isUnit(l.next()) {
+			// accept the whole unit
+		}
+		l.backup()
+		if !slices.Contains(l.config.units, l.slice(unit-l.offset, l.pos)) {
+			return l.errorf(ErrNumberSyntax, expectNumber, "number syntax: %q", l.slice(l.start, l.pos))
+		}
+		l.emit(TokenMetaQuantityValue)
+		return lexInsideMeta
 	}
-	// the next rune must not be a letter
-	if isLetter(l.peek()) {
+	if l.isIdentifierStart(l.peek()) {
 		l.next()
-		return l.errorf("number syntax: %q", l.input[l.start:l.pos])
+		return l.errorf(ErrNumberSyntax, expectNumber, "number syntax: %q", l.slice(l.start, l.pos))
 	}
 	l.emit(TokenMetaNumberValue)
 	return lexInsideMeta
 }
 
+// numberErrorf returns a number syntax error with the reason the number is invalid
+func (l *lexer) numberErrorf(format string, args ...interface{}) stateFn {
+	return l.errorf(ErrNumberSyntax, expectNumber, "number syntax: %q: %s", l.slice(l.start, l.pos), fmt.Sprintf(format, args...))
+}
+
+// literalName names the kind of number literal that starts with prefix
+func literalName(prefix rune) string {
+	switch prefix {
+	case 'x':
+		return "hexadecimal literal"
+	case 'o', '0':
+		return "octal literal"
+	case 'b':
+		return "binary literal"
+	}
+	return "decimal literal"
+}
+
+// validSeparators reports whether every '_' in the number literal sits
+// between two digits, or between the base prefix and a digit
+func validSeparators(literal string) bool {
+	hex := false
+	previous := '.' // '0' for a digit, '_' for a separator, '.' for anything else
+	if len(literal) >= 2 && literal[0] == '0' {
+		switch unicode.ToLower(rune(literal[1])) {
+		case 'x':
+			hex = true
+			fallthrough
+		case 'o', 'b':
+			previous = '0' // the prefix counts as a digit
+			literal = literal[2:]
+		}
+	}
+
+	for _, r := range literal {
+		switch {
+		case r == '_':
+			if previous != '0' {
+				return false
+			}
+			previous = '_'
+		case isNumber(r) || hex && isHex(r):
+			previous = '0'
+		default:
+			if previous == '_' {
+				return false
+			}
+			previous = '.'
+		}
+	}
+	return previous != '_'
+}
+
+// lexMetaTextValue identifies a bare value inside the metadata,
+// which is a bool or null value when it is one of their keywords
 func lexMetaTextValue(l *lexer) stateFn {
-	l.acceptRun(_Letters)
-	l.emit(TokenMetaTextValue)
+	l.acceptRun(_Identifier)
+	word := l.slice(l.start, l.pos)
+	switch {
+	case slices.Contains(l.config.trueWords, word) || slices.Contains(l.config.falseWords, word):
+		l.emit(TokenMetaBoolValue)
+	case slices.Contains(l.config.nullWords, word):
+		l.emit(TokenMetaNullValue)
+	default:
+		l.emit(TokenMetaTextValue)
+	}
+	return lexInsideMeta
+}
+
+// lexMetaStringValue identifies a quoted value inside the metadata.
+// Double and single quoted values may contain escape sequences and
+// end at the line; back quoted values are raw and may span lines.
+func lexMetaStringValue(l *lexer) stateFn {
+	quote := l.next()
+	for {
+		switch r := l.next(); {
+		case r == _EOF || r == _NEWLINE && quote != '`':
+			l.backup()
+			return l.errorf(ErrStringSyntax, expectString, "string syntax: %q", l.slice(l.start, l.pos))
+		case r == '\\' && quote != '`':
+			if l.next() == _EOF {
+				return l.errorf(ErrStringSyntax, expectString, "string syntax: %q", l.slice(l.start, l.pos))
+			}
+		case r == quote:
+			if _, err := unquote(l.slice(l.start, l.pos)); err != nil {
+				return l.errorf(ErrStringSyntax, expectString, "string syntax: %q", l.slice(l.start, l.pos))
+			}
+			l.emit(TokenMetaStringValue)
+			return lexInsideMeta
+		}
+	}
+}
+
+// lexLineComment identifies a comment that runs up to the
+// right meta or the end of the line inside the meta tags
+func lexLineComment(l *lexer) stateFn {
+	l.skip(len(l.config.lineComment))
+	for !l.hasPrefix(l.config.rightMeta) {
+		if r := l.next(); r == _EOF || r == _NEWLINE {
+			l.backup()
+			break
+		}
+	}
+	l.emitTrivia(TokenComment)
 	return lexInsideMeta
 }
+
+// lexBlockComment identifies a comment between the block comment markers
+// inside the meta tags
+func lexBlockComment(l *lexer) stateFn {
+	l.skip(len(l.config.blockCommentOpen))
+	for !l.hasPrefix(l.config.blockCommentClose) {
+		if l.next() == _EOF {
+			return l.errorf(ErrUnclosedComment, expectComment, "unclosed comment")
+		}
+	}
+	l.skip(len(l.config.blockCommentClose))
+	l.emitTrivia(TokenComment)
+	return lexInsideMeta
+}
+
+// lexRecover skips the rest of a meta block after a syntax error, up to its
+// right meta, the next left meta or the end of the line, whichever comes
+// first. When meta blocks may span lines, the end of the line is skipped.
+func lexRecover(l *lexer) stateFn {
+	for {
+		if l.hasPrefix(l.config.rightMeta) {
+			l.ignore()
+			return lexRightMeta
+		}
+		if l.hasPrefix(l.config.leftMeta) {
+			l.ignore()
+			return lexText
+		}
+		switch l.next() {
+		case _EOF:
+			l.ignore()
+			return lexText
+		case _NEWLINE:
+			if !l.config.multiline {
+				l.backup()
+				l.ignore()
+				return lexText
+			}
+		}
+	}
+}
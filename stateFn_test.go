@@ -30,6 +30,7 @@ func TestSetMetaIndicatorMatch(t *testing.T) {
 func TestSetMetaWorking(t *testing.T) {
 	err := lexer.SetMeta("<<", ">>", '=', '|')
 	assert.Nil(t, err)
+	defer lexer.SetMeta("{{", "}}", ':', ',')
 
 	l := lexer.Create("text <<a=5|b=abc>> end.")
 	l.Run(context.Background())
@@ -68,3 +69,37 @@ func TestSetMetaWorking(t *testing.T) {
 	token = l.NextToken()
 	assert.Equal(t, lexer.TokenUndefined, token.Type)
 }
+
+func TestSetMetaDoesNotChangeExistingLexer(t *testing.T) {
+	l := lexer.Create("{{a:b}}")
+
+	err := lexer.SetMeta("<<", ">>", '=', '|')
+	assert.Nil(t, err)
+	defer lexer.SetMeta("{{", "}}", ':', ',')
+
+	l.Run(context.Background())
+
+	token := l.NextToken()
+	assert.Equal(t, lexer.TokenLeftMeta, token.Type)
+	assert.Equal(t, "{{", token.Value)
+}
+
+func TestSetMetaKeepsOtherDefaults(t *testing.T) {
+	err := lexer.SetMeta("<<", ">>", '=', '|')
+	assert.Nil(t, err)
+	defer lexer.SetMeta("{{", "}}", ':', ',')
+
+	l := lexer.Create("<<a=true|b=null|c=5s>>")
+	var types []lexer.TokenType
+	for l.Scan() {
+		types = append(types, l.Token().Type)
+	}
+	assert.Nil(t, l.Err())
+	assert.Equal(t, []lexer.TokenType{
+		lexer.TokenLeftMeta,
+		lexer.TokenMetaIdentifier, lexer.TokenMetaBoolValue,
+		lexer.TokenMetaIdentifier, lexer.TokenMetaNullValue,
+		lexer.TokenMetaIdentifier, lexer.TokenMetaQuantityValue,
+		lexer.TokenRightMeta,
+	}, types)
+}
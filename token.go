@@ -1,5 +1,14 @@
 package lexer
 
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
 type TokenType int
 
 const (
@@ -12,11 +21,36 @@ const (
 	TokenRightMeta
 	TokenError
 	TokenEof
+	TokenMetaStringValue
+	TokenComment
+	TokenMetaBoolValue
+	TokenMetaNullValue
+	TokenMetaQuantityValue
+	TokenWhitespace
+	TokenSeparator
+	TokenValueIndicator
 )
 
+// Position is a location in the input. Line and Column are 1-based,
+// and Column is counted in runes.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Token is a lexeme found in the input. Start and End span the lexeme,
+// End being the position just after its last rune.
 type Token struct {
 	Type  TokenType
 	Value string
+	Start Position
+	End   Position
+	Err   error // the *LexError of a TokenError
 }
 
 func (t Token) String() string {
@@ -26,6 +60,183 @@ func (t Token) String() string {
 	return t.Value
 }
 
+// Unquote returns the text of a TokenMetaStringValue without its quotes
+// and with its escape sequences, which follow Go, interpreted.
+// Back quoted values are returned as they are. Other tokens return Value.
+func (t Token) Unquote() (string, error) {
+	if t.Type != TokenMetaStringValue {
+		return t.Value, nil
+	}
+	return unquote(t.Value)
+}
+
+// Path returns the dot separated parts of a TokenMetaIdentifier,
+// such as ["page", "title"] for page.title. Other tokens return nil.
+func (t Token) Path() []string {
+	if t.Type != TokenMetaIdentifier {
+		return nil
+	}
+	return strings.Split(t.Value, ".")
+}
+
+// Quantity returns the number and the unit of a TokenMetaQuantityValue,
+// such as "1.5" and "GiB" for 1.5GiB. A TokenMetaNumberValue has no unit.
+func (t Token) Quantity() (number, unit string) {
+	switch t.Type {
+	case TokenMetaNumberValue:
+		return t.Value, ""
+	case TokenMetaQuantityValue:
+		// units follow decimal numbers only, which end in a digit or a point
+		split := strings.LastIndexAny(t.Value, "0123456789.") + 1
+		return t.Value[:split], t.Value[split:]
+	}
+	return "", ""
+}
+
+// Int64 returns the value of a TokenMetaNumberValue that is an integer,
+// interpreting its base prefix as Go does. For a TokenMetaQuantityValue
+// it returns the value of the number without its unit.
+func (t Token) Int64() (int64, error) {
+	number, _ := t.Quantity()
+	if number == "" {
+		return 0, &strconv.NumError{Func: "Int64", Num: t.Value, Err: strconv.ErrSyntax}
+	}
+	return strconv.ParseInt(number, 0, 64)
+}
+
+// Float64 returns the value of a TokenMetaNumberValue as a float64.
+// An integer is converted as Go converts an untyped constant, so 017 is 15.
+// For a TokenMetaQuantityValue it returns the value of the number without
+// its unit.
+func (t Token) Float64() (float64, error) {
+	number, _ := t.Quantity()
+	if number == "" {
+		return 0, &strconv.NumError{Func: "Float64", Num: t.Value, Err: strconv.ErrSyntax}
+	}
+	if integer, ok := new(big.Int).SetString(number, 0); ok {
+		value, _ := new(big.Float).SetInt(integer).Float64()
+		return value, nil
+	}
+	return strconv.ParseFloat(number, 64)
+}
+
+// BigFloat returns the value of a TokenMetaNumberValue without losing
+// precision to a float64. For a TokenMetaQuantityValue it returns the
+// value of the number without its unit.
+func (t Token) BigFloat() (*big.Float, error) {
+	number, _ := t.Quantity()
+	if number == "" {
+		return nil, &strconv.NumError{Func: "BigFloat", Num: t.Value, Err: strconv.ErrSyntax}
+	}
+	if integer, ok := new(big.Int).SetString(number, 0); ok {
+		return new(big.Float).SetInt(integer), nil
+	}
+	value, _, err := big.ParseFloat(number, 0, 0, big.ToNearestEven)
+	return value, err
+}
+
+// durationUnits are the units Duration understands
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"µs": time.Microsecond, // U+00B5 micro sign
+	"μs": time.Microsecond, // U+03BC Greek letter mu
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// byteSizeUnits are the units ByteSize understands, in decimal and binary multiples
+var byteSizeUnits = map[string]int64{
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+	"PiB": 1 << 50,
+}
+
+// Duration returns the value of a TokenMetaQuantityValue with a unit of
+// time, from ns to h, as a time.Duration. Fractions of a nanosecond are dropped.
+func (t Token) Duration() (time.Duration, error) {
+	_, unit := t.Quantity()
+	scale, ok := durationUnits[unit]
+	if !ok {
+		return 0, &strconv.NumError{Func: "Duration", Num: t.Value, Err: strconv.ErrSyntax}
+	}
+	value, _, err := t.scaled("Duration", int64(scale))
+	return time.Duration(value), err
+}
+
+// ByteSize returns the number of bytes of a TokenMetaQuantityValue with a
+// unit of B, KB, MB, GB, TB or PB, which are powers of 1000, or KiB, MiB,
+// GiB, TiB or PiB, which are powers of 1024. The size must be whole bytes.
+func (t Token) ByteSize() (int64, error) {
+	_, unit := t.Quantity()
+	scale, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, &strconv.NumError{Func: "ByteSize", Num: t.Value, Err: strconv.ErrSyntax}
+	}
+	value, exact, err := t.scaled("ByteSize", scale)
+	if err == nil && !exact {
+		return 0, &strconv.NumError{Func: "ByteSize", Num: t.Value, Err: strconv.ErrSyntax}
+	}
+	return value, err
+}
+
+// scaled returns the number of a quantity multiplied by scale, truncated
+// toward zero, and whether nothing was truncated
+func (t Token) scaled(function string, scale int64) (value int64, exact bool, err error) {
+	number, _ := t.Quantity()
+	rational := new(big.Rat)
+	if integer, ok := new(big.Int).SetString(number, 0); ok {
+		rational.SetInt(integer)
+	} else if _, ok := rational.SetString(number); !ok {
+		return 0, false, &strconv.NumError{Func: function, Num: t.Value, Err: strconv.ErrSyntax}
+	}
+
+	rational.Mul(rational, new(big.Rat).SetInt64(scale))
+	truncated := new(big.Int).Quo(rational.Num(), rational.Denom())
+	if !truncated.IsInt64() {
+		return 0, false, &strconv.NumError{Func: function, Num: t.Value, Err: strconv.ErrRange}
+	}
+	return truncated.Int64(), rational.IsInt(), nil
+}
+
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != s[len(s)-1] || !isQuote(rune(s[0])) {
+		return "", strconv.ErrSyntax
+	}
+	quote, body := s[0], s[1:len(s)-1]
+	if quote == '`' {
+		if strings.ContainsRune(body, '`') {
+			return "", strconv.ErrSyntax
+		}
+		return body, nil
+	}
+
+	var unquoted strings.Builder
+	for len(body) > 0 {
+		value, multibyte, tail, err := strconv.UnquoteChar(body, quote)
+		if err != nil {
+			return "", err
+		}
+		if value < utf8.RuneSelf || !multibyte {
+			unquoted.WriteByte(byte(value))
+		} else {
+			unquoted.WriteRune(value)
+		}
+		body = tail
+	}
+	return unquoted.String(), nil
+}
+
 func (t TokenType) String() string {
 	switch t {
 	case TokenUndefined:
@@ -46,6 +257,22 @@ func (t TokenType) String() string {
 		return "Error"
 	case TokenEof:
 		return "Eof"
+	case TokenMetaStringValue:
+		return "MetaStringValue"
+	case TokenComment:
+		return "Comment"
+	case TokenMetaBoolValue:
+		return "MetaBoolValue"
+	case TokenMetaNullValue:
+		return "MetaNullValue"
+	case TokenMetaQuantityValue:
+		return "MetaQuantityValue"
+	case TokenWhitespace:
+		return "Whitespace"
+	case TokenSeparator:
+		return "Separator"
+	case TokenValueIndicator:
+		return "ValueIndicator"
 	}
 	return "invalid"
 }
@@ -1,7 +1,10 @@
 package lexer_test
 
 import (
+	"errors"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/adroge/lexer"
 	"github.com/stretchr/testify/assert"
@@ -56,3 +59,199 @@ func TestTokenTypeStringInvalid(t *testing.T) {
 	tok := lexer.TokenEof + 10000
 	assert.Equal(t, "invalid", tok.String())
 }
+
+func TestPositionString(t *testing.T) {
+	pos := lexer.Position{Offset: 12, Line: 3, Column: 7}
+	assert.Equal(t, "3:7", pos.String())
+}
+
+func TestTokenTypeStringMetaStringValue(t *testing.T) {
+	tok := lexer.TokenMetaStringValue
+	assert.Equal(t, "MetaStringValue", tok.String())
+}
+
+func TestTokenUnquote(t *testing.T) {
+	tests := map[string]string{
+		`"tab\there"`:       "tab\there",
+		`"\u00e9t\xc3\xa9"`: "été",
+		`'single "double"'`: `single "double"`,
+		`'it\'s'`:           "it's",
+		"`raw\\n`":          `raw\n`,
+		`""`:                "",
+	}
+	for value, expected := range tests {
+		unquoted, err := lexer.Token{Type: lexer.TokenMetaStringValue, Value: value}.Unquote()
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, unquoted, value)
+	}
+}
+
+func TestTokenUnquoteInvalid(t *testing.T) {
+	for _, value := range []string{`"`, `"abc'`, `abc`, "`a`b`", `"\z"`} {
+		_, err := lexer.Token{Type: lexer.TokenMetaStringValue, Value: value}.Unquote()
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestTokenUnquoteOtherTypes(t *testing.T) {
+	unquoted, err := lexer.Token{Type: lexer.TokenMetaTextValue, Value: `"abc"`}.Unquote()
+	assert.Nil(t, err)
+	assert.Equal(t, `"abc"`, unquoted)
+}
+
+func TestTokenPath(t *testing.T) {
+	assert.Equal(t, []string{"a"}, lexer.Token{Type: lexer.TokenMetaIdentifier, Value: "a"}.Path())
+	assert.Equal(t, []string{"a", "b", "c"}, lexer.Token{Type: lexer.TokenMetaIdentifier, Value: "a.b.c"}.Path())
+	assert.Nil(t, lexer.Token{Type: lexer.TokenMetaTextValue, Value: "a.b"}.Path())
+}
+
+func TestTokenTypeStringComment(t *testing.T) {
+	tok := lexer.TokenComment
+	assert.Equal(t, "Comment", tok.String())
+}
+
+func TestTokenTypeStringMetaBoolValue(t *testing.T) {
+	tok := lexer.TokenMetaBoolValue
+	assert.Equal(t, "MetaBoolValue", tok.String())
+}
+
+func TestTokenTypeStringMetaNullValue(t *testing.T) {
+	tok := lexer.TokenMetaNullValue
+	assert.Equal(t, "MetaNullValue", tok.String())
+}
+
+func TestTokenInt64(t *testing.T) {
+	tests := map[string]int64{
+		"42": 42, "-42": -42, "+0x2a": 42, "0o52": 42, "052": 42, "0b101010": 42, "1_000": 1000,
+	}
+	for value, expected := range tests {
+		number, err := lexer.Token{Type: lexer.TokenMetaNumberValue, Value: value}.Int64()
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, number, value)
+	}
+
+	_, err := lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "4.2"}.Int64()
+	assert.NotNil(t, err)
+
+	_, err = lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "9223372036854775808"}.Int64()
+	assert.True(t, errors.Is(err, strconv.ErrRange))
+
+	_, err = lexer.Token{Type: lexer.TokenMetaTextValue, Value: "42"}.Int64()
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+}
+
+func TestTokenFloat64(t *testing.T) {
+	tests := map[string]float64{
+		"42": 42, "017": 15, "4.2": 4.2, "1e9": 1e9, "-1_000.5": -1000.5, "0x1p-2": 0.25, "1.": 1, "0b11": 3,
+	}
+	for value, expected := range tests {
+		number, err := lexer.Token{Type: lexer.TokenMetaNumberValue, Value: value}.Float64()
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, number, value)
+	}
+
+	_, err := lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "1e400"}.Float64()
+	assert.True(t, errors.Is(err, strconv.ErrRange))
+
+	_, err = lexer.Token{Type: lexer.TokenMetaStringValue, Value: `"1"`}.Float64()
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+}
+
+func TestTokenBigFloat(t *testing.T) {
+	number, err := lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "123456789012345678901234567890"}.BigFloat()
+	assert.Nil(t, err)
+	assert.Equal(t, "123456789012345678901234567890", number.Text('f', 0))
+
+	number, err = lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "0x1.8p1"}.BigFloat()
+	assert.Nil(t, err)
+	assert.Equal(t, "3", number.Text('f', 0))
+
+	number, err = lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "017"}.BigFloat()
+	assert.Nil(t, err)
+	assert.Equal(t, "15", number.Text('f', 0))
+
+	_, err = lexer.Token{Type: lexer.TokenMetaIdentifier, Value: "a"}.BigFloat()
+	assert.NotNil(t, err)
+}
+
+func TestTokenTypeStringMetaQuantityValue(t *testing.T) {
+	tok := lexer.TokenMetaQuantityValue
+	assert.Equal(t, "MetaQuantityValue", tok.String())
+}
+
+func TestTokenQuantity(t *testing.T) {
+	number, unit := lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: "1.5e3ms"}.Quantity()
+	assert.Equal(t, "1.5e3", number)
+	assert.Equal(t, "ms", unit)
+
+	number, unit = lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: "5EB"}.Quantity()
+	assert.Equal(t, "5", number)
+	assert.Equal(t, "EB", unit)
+
+	number, unit = lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "42"}.Quantity()
+	assert.Equal(t, "42", number)
+	assert.Equal(t, "", unit)
+
+	value, err := lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: "50%"}.Float64()
+	assert.Nil(t, err)
+	assert.Equal(t, 50.0, value)
+
+	integer, err := lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: "-12px"}.Int64()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-12), integer)
+}
+
+func TestTokenDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"30s":   30 * time.Second,
+		"1.5h":  90 * time.Minute,
+		"250ms": 250 * time.Millisecond,
+		"-2m":   -2 * time.Minute,
+		"1e3us": time.Millisecond,
+		"1.5ns": time.Nanosecond,
+		"10µs":  10 * time.Microsecond,
+	}
+	for value, expected := range tests {
+		duration, err := lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: value}.Duration()
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, duration, value)
+	}
+
+	_, err := lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: "10MB"}.Duration()
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+
+	_, err = lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: "9999999999h"}.Duration()
+	assert.True(t, errors.Is(err, strconv.ErrRange))
+}
+
+func TestTokenByteSize(t *testing.T) {
+	tests := map[string]int64{
+		"10MB":    10_000_000,
+		"1.5KiB":  1536,
+		"2GiB":    2 << 30,
+		"512B":    512,
+		"1.1KB":   1100,
+		"0.5PiB":  1 << 49,
+		"1_000KB": 1_000_000,
+	}
+	for value, expected := range tests {
+		size, err := lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: value}.ByteSize()
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+
+	_, err := lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: "1.5B"}.ByteSize()
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+
+	_, err = lexer.Token{Type: lexer.TokenMetaQuantityValue, Value: "30s"}.ByteSize()
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+
+	_, err = lexer.Token{Type: lexer.TokenMetaNumberValue, Value: "30"}.ByteSize()
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+}
+
+func TestTokenTypeStringTrivia(t *testing.T) {
+	assert.Equal(t, "Whitespace", lexer.TokenWhitespace.String())
+	assert.Equal(t, "Separator", lexer.TokenSeparator.String())
+	assert.Equal(t, "ValueIndicator", lexer.TokenValueIndicator.String())
+}
@@ -0,0 +1,319 @@
+package lexer
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// metaField is a struct field with a meta tag
+type metaField struct {
+	key       string
+	index     []int
+	optional  bool // Unmarshal does not require the key
+	omitEmpty bool // Marshal leaves the key out when the value is empty
+}
+
+// metaFields returns the fields of t with a meta tag, in order. Fields
+// without a tag, tagged "-" or not exported are left out.
+func metaFields(t reflect.Type) (fields []metaField) {
+	for _, field := range reflect.VisibleFields(t) {
+		tag := field.Tag.Get("meta")
+		if !field.IsExported() || tag == "" || tag == "-" {
+			continue
+		}
+		key, list, _ := strings.Cut(tag, ",")
+		options := strings.Split(list, ",")
+		fields = append(fields, metaField{
+			key:       key,
+			index:     field.Index,
+			optional:  slices.Contains(options, "optional"),
+			omitEmpty: slices.Contains(options, "omitempty"),
+		})
+	}
+	return
+}
+
+// Unmarshal lexes input with opts and stores the entries of its meta blocks
+// in the struct that v points to.
+//
+//	var page struct {
+//		Title string        `meta:"title"`
+//		Width int           `meta:"width,optional"`
+//		Tags  []string      `meta:"tag,optional"`
+//		Delay time.Duration `meta:"delay"`
+//	}
+//	err := lexer.Unmarshal(`{{title: "Home", tag: a, tag: b, delay: 2s}}`, &page)
+func Unmarshal(input string, v any, opts ...Option) error {
+	l := Create(input, opts...)
+	return l.Unmarshal(v)
+}
+
+// Unmarshal stores the entries of the remaining meta blocks in the struct
+// that v points to. Each entry is stored in the field whose meta tag names
+// its key; fields without a tag are left alone.
+//
+// Values are converted to the type of the field: text, numbers, quantities
+// and quoted values to a string, bool values to a bool, with a key given
+// without a value meaning true, integers to the integer types, numbers to
+// the float types and quantities of time to a time.Duration. A field whose
+// pointer implements encoding.TextUnmarshaler is given the text of the
+// value. A null value stores the zero value. A pointer field is allocated
+// as needed, as are the embedded pointers a field is promoted through. A
+// slice field is given an element for each time its key appears; other
+// fields keep the last value.
+//
+// Every tagged field must be given, unless its tag has the optional option,
+// as in `meta:"width,optional"`, and every key must have a field. These
+// and values that cannot be converted are reported as *UnmarshalError,
+// joined; a syntax error is reported as by Err instead.
+func (l *lexer) Unmarshal(v any) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("lexer: cannot unmarshal into %T, need a non-nil pointer to a struct", v)
+	}
+	target = target.Elem()
+
+	fields := metaFields(target.Type())
+	byKey := make(map[string]metaField, len(fields))
+	for _, field := range fields {
+		byKey[field.key] = field
+	}
+
+	var (
+		errs  []error
+		given = make(map[string]bool)
+		key   Token // identifier waiting for its value
+	)
+	store := func(value Token) {
+		if key.Type != TokenMetaIdentifier {
+			return
+		}
+		if err := l.store(target, byKey, key, value, given); err != nil {
+			errs = append(errs, err)
+		}
+		key = Token{}
+	}
+	for l.Scan() {
+		switch token := l.Token(); token.Type {
+		case TokenMetaIdentifier:
+			store(Token{})
+			key = token
+		case TokenMetaNumberValue, TokenMetaTextValue, TokenMetaStringValue,
+			TokenMetaBoolValue, TokenMetaNullValue, TokenMetaQuantityValue:
+			store(token)
+		case TokenRightMeta:
+			store(Token{})
+		case TokenError:
+			key = Token{}
+		}
+	}
+	if err := l.Err(); err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if !field.optional && !given[field.key] {
+			errs = append(errs, &UnmarshalError{
+				Kind:    ErrMissingKey,
+				Key:     field.key,
+				Message: fmt.Sprintf("missing key %q", field.key),
+			})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// store converts value and stores it in the field of target for key
+func (l *lexer) store(target reflect.Value, fields map[string]metaField, key, value Token, given map[string]bool) error {
+	field, ok := fields[key.Value]
+	if !ok {
+		return &UnmarshalError{
+			Kind:    ErrUnknownKey,
+			Key:     key.Value,
+			Pos:     key.Start,
+			Message: fmt.Sprintf("unknown key %q", key.Value),
+		}
+	}
+
+	destination, ok := fieldByIndex(target, field.index)
+	if !ok {
+		return &UnmarshalError{
+			Kind:    ErrValueType,
+			Key:     key.Value,
+			Pos:     key.Start,
+			Message: fmt.Sprintf("%s: cannot set embedded pointer to unexported struct", key.Value),
+		}
+	}
+	if repeated(destination.Type()) {
+		if !given[key.Value] {
+			destination.SetLen(0)
+		}
+		element := reflect.New(destination.Type().Elem()).Elem()
+		err := l.convert(element, value)
+		if err == nil {
+			destination.Set(reflect.Append(destination, element))
+		}
+		given[key.Value] = true
+		return valueError(key, value, element.Type(), err)
+	}
+	given[key.Value] = true
+	return valueError(key, value, destination.Type(), l.convert(destination, value))
+}
+
+// fieldByIndex returns the field of target at index, allocating the nil
+// embedded pointers it is promoted through. ok is false when one of them
+// points to an unexported struct, which cannot be allocated.
+func fieldByIndex(target reflect.Value, index []int) (field reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && target.Kind() == reflect.Pointer {
+			if target.IsNil() {
+				if !target.CanSet() {
+					return reflect.Value{}, false
+				}
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
+		}
+		target = target.Field(x)
+	}
+	return target, true
+}
+
+// repeated reports whether a field of type t is given an element for each
+// time its key appears, rather than keeping the last value
+func repeated(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// convert stores value in destination, returning ErrValueType when the
+// value does not suit its type
+func (l *lexer) convert(destination reflect.Value, value Token) error {
+	if value.Type == TokenMetaNullValue {
+		destination.SetZero()
+		return nil
+	}
+	if destination.Kind() == reflect.Pointer {
+		if destination.IsNil() {
+			destination.Set(reflect.New(destination.Type().Elem()))
+		}
+		return l.convert(destination.Elem(), value)
+	}
+
+	if unmarshaler, ok := destination.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if value.Type == TokenUndefined {
+			return ErrValueType
+		}
+		text, err := value.Unquote()
+		if err != nil {
+			return err
+		}
+		return unmarshaler.UnmarshalText([]byte(text))
+	}
+
+	if destination.Type() == durationType {
+		if value.Type != TokenMetaQuantityValue {
+			return ErrValueType
+		}
+		duration, err := value.Duration()
+		if err != nil {
+			return err
+		}
+		destination.SetInt(int64(duration))
+		return nil
+	}
+
+	switch destination.Kind() {
+	case reflect.String:
+		if value.Type == TokenUndefined {
+			return ErrValueType
+		}
+		text, err := value.Unquote()
+		if err != nil {
+			return err
+		}
+		destination.SetString(text)
+	case reflect.Bool:
+		if value.Type == TokenUndefined {
+			destination.SetBool(true)
+			return nil
+		}
+		boolean, ok := l.Bool(value)
+		if !ok {
+			return ErrValueType
+		}
+		destination.SetBool(boolean)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value.Type != TokenMetaNumberValue {
+			return ErrValueType
+		}
+		integer, err := value.Int64()
+		if err != nil {
+			return err
+		}
+		if destination.OverflowInt(integer) {
+			return &strconv.NumError{Func: "ParseInt", Num: value.Value, Err: strconv.ErrRange}
+		}
+		destination.SetInt(integer)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if value.Type != TokenMetaNumberValue {
+			return ErrValueType
+		}
+		integer, err := strconv.ParseUint(strings.TrimPrefix(value.Value, "+"), 0, 64)
+		if err != nil {
+			return err
+		}
+		if destination.OverflowUint(integer) {
+			return &strconv.NumError{Func: "ParseUint", Num: value.Value, Err: strconv.ErrRange}
+		}
+		destination.SetUint(integer)
+	case reflect.Float32, reflect.Float64:
+		if value.Type != TokenMetaNumberValue {
+			return ErrValueType
+		}
+		float, err := value.Float64()
+		if err != nil {
+			return err
+		}
+		if destination.OverflowFloat(float) {
+			return &strconv.NumError{Func: "ParseFloat", Num: value.Value, Err: strconv.ErrRange}
+		}
+		destination.SetFloat(float)
+	default:
+		return ErrValueType
+	}
+	return nil
+}
+
+// valueError returns the UnmarshalError for value of key that could not be
+// converted to t, or nil when err is nil
+func valueError(key, value Token, t reflect.Type, err error) error {
+	if err == nil {
+		return nil
+	}
+	given := "no value"
+	if value.Type != TokenUndefined {
+		given = fmt.Sprintf("%s %q", value.Type, value.Value)
+	}
+	unmarshalErr := &UnmarshalError{
+		Kind:    ErrValueType,
+		Key:     key.Value,
+		Pos:     key.Start,
+		Message: fmt.Sprintf("%s: cannot use %s as %s", key.Value, given, t),
+	}
+	if err != ErrValueType {
+		unmarshalErr.cause = err
+		unmarshalErr.Message += ": " + err.Error()
+	}
+	return unmarshalErr
+}
@@ -0,0 +1,156 @@
+package lexer_test
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/adroge/lexer"
+)
+
+type page struct {
+	Title   string        `meta:"title"`
+	Width   int           `meta:"width"`
+	Ratio   float64       `meta:"ratio,optional"`
+	Draft   bool          `meta:"draft,optional"`
+	Public  bool          `meta:"public,optional"`
+	Delay   time.Duration `meta:"delay,optional"`
+	Tags    []string      `meta:"tag,optional"`
+	Sizes   []uint8       `meta:"size,optional"`
+	Author  *string       `meta:"page.author,optional"`
+	Address netip.Addr    `meta:"address,optional"`
+	Ignored string
+	Skipped string `meta:"-"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	input := `Title {{title: "Home page", width: 0x10, ratio: 1.5, draft}} body
+{{public: yes, delay: 1.5s, tag: a, tag: 'b c', size: 1, size: 2}}
+{{page.author: ann, address: "10.0.0.1"}}`
+	var p page
+	err := lexer.Unmarshal(input, &p, lexer.WithBoolKeywords([]string{"yes"}, []string{"no"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "Home page", p.Title)
+	assert.Equal(t, 16, p.Width)
+	assert.Equal(t, 1.5, p.Ratio)
+	assert.True(t, p.Draft)
+	assert.True(t, p.Public)
+	assert.Equal(t, 1500*time.Millisecond, p.Delay)
+	assert.Equal(t, []string{"a", "b c"}, p.Tags)
+	assert.Equal(t, []uint8{1, 2}, p.Sizes)
+	if assert.NotNil(t, p.Author) {
+		assert.Equal(t, "ann", *p.Author)
+	}
+	assert.Equal(t, netip.MustParseAddr("10.0.0.1"), p.Address)
+}
+
+func TestUnmarshalNull(t *testing.T) {
+	author := "someone"
+	p := page{Author: &author, Tags: []string{"old"}}
+	err := lexer.Unmarshal("{{title: null, width: 1, page.author: null, tag: new}}", &p)
+	assert.Nil(t, err)
+	assert.Equal(t, "", p.Title)
+	assert.Nil(t, p.Author)
+	assert.Equal(t, []string{"new"}, p.Tags)
+}
+
+func TestUnmarshalKeys(t *testing.T) {
+	var p page
+	err := lexer.Unmarshal("{{title: x, height: 3}}", &p)
+
+	var unmarshalErrs []*lexer.UnmarshalError
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var unmarshalErr *lexer.UnmarshalError
+		if assert.True(t, errors.As(err, &unmarshalErr)) {
+			unmarshalErrs = append(unmarshalErrs, unmarshalErr)
+		}
+	}
+	assert.Len(t, unmarshalErrs, 2)
+	assert.True(t, errors.Is(unmarshalErrs[0], lexer.ErrUnknownKey))
+	assert.Equal(t, "height", unmarshalErrs[0].Key)
+	assert.Equal(t, lexer.Position{Offset: 12, Line: 1, Column: 13}, unmarshalErrs[0].Pos)
+	assert.Equal(t, `1:13: unknown key "height"`, unmarshalErrs[0].Error())
+	assert.True(t, errors.Is(unmarshalErrs[1], lexer.ErrMissingKey))
+	assert.Equal(t, `missing key "width"`, unmarshalErrs[1].Error())
+	assert.Equal(t, "x", p.Title)
+}
+
+func TestUnmarshalValueTypes(t *testing.T) {
+	tests := map[string]string{
+		"{{title: t, width: abc}}":           `1:13: width: cannot use MetaTextValue "abc" as int`,
+		"{{title: t, width}}":                `1:13: width: cannot use no value as int`,
+		"{{title, width: 1}}":                `1:3: title: cannot use no value as string`,
+		"{{title: t, width: 1.5}}":           `1:13: width: cannot use MetaNumberValue "1.5" as int: strconv.ParseInt: parsing "1.5": invalid syntax`,
+		"{{title: t, width: 1, size: -1}}":   `1:23: size: cannot use MetaNumberValue "-1" as uint8: strconv.ParseUint: parsing "-1": invalid syntax`,
+		"{{title: t, width: 1, size: 300}}":  `1:23: size: cannot use MetaNumberValue "300" as uint8: strconv.ParseUint: parsing "300": value out of range`,
+		"{{title: t, width: 1, delay: 10}}":  `1:23: delay: cannot use MetaNumberValue "10" as time.Duration`,
+		"{{title: t, width: 1, delay: 5MB}}": `1:23: delay: cannot use MetaQuantityValue "5MB" as time.Duration: strconv.Duration: parsing "5MB": invalid syntax`,
+		"{{title: t, width: 1, draft: on}}":  `1:23: draft: cannot use MetaTextValue "on" as bool`,
+	}
+	for input, message := range tests {
+		var p page
+		err := lexer.Unmarshal(input, &p)
+		assert.True(t, errors.Is(err, lexer.ErrValueType), input)
+		if assert.NotNil(t, err, input) {
+			assert.Equal(t, message, err.Error(), input)
+		}
+	}
+
+	var p page
+	err := lexer.Unmarshal("{{title: t, width: 99999999999999999999}}", &p)
+	assert.True(t, errors.Is(err, strconv.ErrRange))
+}
+
+func TestUnmarshalSyntaxError(t *testing.T) {
+	var p page
+	err := lexer.Unmarshal("{{title: t, width: *}}", &p)
+	assert.True(t, errors.Is(err, lexer.ErrValueSyntax))
+	assert.False(t, errors.Is(err, lexer.ErrMissingKey))
+}
+
+func TestUnmarshalTarget(t *testing.T) {
+	var p page
+	assert.NotNil(t, lexer.Unmarshal("{{title: t}}", p))
+	assert.NotNil(t, lexer.Unmarshal("{{title: t}}", (*page)(nil)))
+	var s string
+	assert.NotNil(t, lexer.Unmarshal("{{title: t}}", &s))
+}
+
+func TestUnmarshalEmbeddedPointer(t *testing.T) {
+	type Inner struct {
+		X int `meta:"x"`
+	}
+	type hidden struct {
+		Z int `meta:"z,optional"`
+	}
+	type outer struct {
+		*Inner
+		*hidden
+		Y int `meta:"y"`
+	}
+
+	var o outer
+	assert.Nil(t, lexer.Unmarshal("{{x: 1, y: 2}}", &o))
+	if assert.NotNil(t, o.Inner) {
+		assert.Equal(t, 1, o.X)
+	}
+	assert.Equal(t, 2, o.Y)
+
+	err := lexer.Unmarshal("{{x: 1, y: 2, z: 3}}", &outer{})
+	assert.True(t, errors.Is(err, lexer.ErrValueType))
+	assert.EqualError(t, err, "1:15: z: cannot set embedded pointer to unexported struct")
+}
+
+func TestUnmarshalReader(t *testing.T) {
+	var config struct {
+		Name string `meta:"name"`
+	}
+	l := lexer.CreateFromReader(strings.NewReader("{{name: `raw text`}}"))
+	assert.Nil(t, l.Unmarshal(&config))
+	assert.Equal(t, "raw text", config.Name)
+}